@@ -2,7 +2,10 @@ package config
 
 import (
     "fmt"
+    "net"
     "net/url"
+    "os"
+    "strings"
     "time"
 )
 
@@ -22,29 +25,162 @@ type Options struct {
     Retry           int
     Headers         map[string]string
     Cookies         string
+    CookieJar       string // Netscape-format cookie file, cookies sent per domain/path
+    BasicAuth       string // user:password for HTTP Basic auth
+    BearerToken     string
+    SessionFile     string // JSON session recipe: login request, logout markers, extraction
     URLsFile        bool
-    Proxy           bool
-    ProxyUrl        string
+    RequestsFile    bool // Wordlist is a raw HTTP request file or a directory of them
+    HostsFile       bool // Hostname is a file with one host, host:port or origin per line
+    MaxHostErrors   int  // consecutive baseline failures before a host is skipped (0 = never)
+    Probe           bool  // detect scheme/port for bare hostnames instead of using Ssl/Port
+    ProbePorts      []int // ports tried by the probe, in order (default 443, 80)
+    RawHTTP         string // raw socket transport: "auto" (default), "always" or "never"
+    Protocol        string // "auto" (default), "http1.0", "http1.1", "h2" or "h2c"
+    MaxBody         int64  // decoded response body bytes kept in memory (0 = default 1 MiB, <0 = no limit)
+    HashBody        bool   // sha256 the full body even when only a prefix is kept
+    Decode          string // Content-Encoding handling: "auto" (default) or "none"
+    ClientCert      string   // PEM client certificate for mTLS (may also hold the key)
+    ClientKey       string   // PEM private key for ClientCert
+    CACert          string   // PEM bundle trusted in addition to the system roots
+    SNI             string   // TLS server name sent instead of the request host
+    Resolve         []string // curl-style host:port:addr connect overrides
+    TrafficFile     string   // traffic log: HAR when the name ends in .har, rotating JSONL otherwise
+    TrafficMode     string   // "all" (default) or "findings" (only exchanges referenced by findings)
+    TrafficMaxBody  int64    // body bytes recorded per request/response (0 = 64 KiB)
+    TrafficRotate   int64    // JSONL size before rotation (0 = 100 MiB)
+    TrafficNoRedact bool     // keep Cookie/Authorization values in the traffic log
+    TrafficRedact   []string // further header names to redact (in the traffic log and findings)
+    NoRedact        bool     // keep Cookie/Authorization values in findings (requests, curl/python, snapshots)
+    Proxies         []string // http(s)://, socks5:// or socks5h:// proxy URLs, optionally with user:password@; HTTP_PROXY/HTTPS_PROXY/NO_PROXY apply when empty
+    ProxyRotate     string   // "round-robin" (default) or "sticky" (one proxy per target host)
+    ProxyAuth       string   // user:password for proxies given without credentials
     OutputDir       string
 }
 
 // BuildBaseURL constructs scheme://host[:port] from Options.
 // It does not append any path – paths are supplied separately per request.
 func (o *Options) BuildBaseURL() (string, error) {
-    if o.Hostname == "" {
+    return o.OriginFor(o.Hostname)
+}
+
+// OriginFor constructs scheme://host[:port] for a single host entry, which may be a bare
+// hostname or IP (IPv6 with or without brackets), host:port or a full origin (a path on
+// a full origin is ignored). Bare hostnames use the configured Ssl and Port. Port 443
+// (and 8443 on an entry) implies https and port 80 implies http, whatever Ssl says.
+func (o *Options) OriginFor(entry string) (string, error) {
+    entry = strings.TrimSpace(entry)
+    if entry == "" {
         return "", fmt.Errorf("hostname is empty")
     }
+    if strings.Contains(entry, "://") {
+        u, err := url.Parse(entry)
+        if err != nil {
+            return "", err
+        }
+        if u.Scheme == "" || u.Host == "" {
+            return "", fmt.Errorf("invalid origin %q", entry)
+        }
+        return u.Scheme + "://" + u.Host, nil
+    }
+    if h, p, err := net.SplitHostPort(entry); err == nil {
+        // host:port – the well-known ports pick the scheme, --ssl decides for the others
+        ssl := p == "443" || p == "8443" || (o.Ssl && p != "80")
+        return JoinOrigin(h, p, ssl), nil
+    }
+    port := ""
+    if o.Port > 0 {
+        port = fmt.Sprint(o.Port)
+    }
+    // http://host:443 or https://host:80 is never what is meant
+    return JoinOrigin(strings.Trim(entry, "[]"), port, o.Port == 443 || (o.Ssl && o.Port != 80)), nil
+}
+
+// JoinOrigin builds scheme://host[:port], omitting the port when it is the scheme default.
+// IPv6 hosts are bracketed.
+func JoinOrigin(host, port string, ssl bool) string {
     scheme := "http"
-    if o.Ssl {
+    if ssl {
         scheme = "https"
     }
-    host := o.Hostname
     // Only append port if it is non-standard for the chosen scheme
-    if (o.Port > 0) && !((!o.Ssl && o.Port == 80) || (o.Ssl && o.Port == 443)) {
-        host = fmt.Sprintf("%s:%d", host, o.Port)
+    if port != "" && !((!ssl && port == "80") || (ssl && port == "443")) {
+        host = net.JoinHostPort(host, port)
+    } else if strings.Contains(host, ":") {
+        host = "[" + host + "]"
     }
-
     u := url.URL{Scheme: scheme, Host: host}
-    return u.String(), nil
+    return u.String()
+}
+
+// AddHeader adds a "Name: value" header line to Headers. A later header with the same
+// name replaces the earlier one.
+func (o *Options) AddHeader(line string) error {
+    name, value, ok := strings.Cut(line, ":")
+    name = strings.TrimSpace(name)
+    if !ok || name == "" || strings.ContainsAny(name, " \t") {
+        return fmt.Errorf("invalid header %q (want \"Name: value\")", line)
+    }
+    if o.Headers == nil {
+        o.Headers = make(map[string]string)
+    }
+    o.Headers[name] = strings.TrimSpace(value)
+    return nil
+}
+
+// LoadHeadersFile adds every "Name: value" line of a file to Headers.
+// Blank lines and lines starting with "#" are ignored.
+func (o *Options) LoadHeadersFile(name string) error {
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return err
+    }
+    for i, line := range strings.Split(string(data), "\n") {
+        line = strings.TrimRight(line, "\r")
+        if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+            continue
+        }
+        if err := o.AddHeader(line); err != nil {
+            return fmt.Errorf("%s:%d: %w", name, i+1, err)
+        }
+    }
+    return nil
 }
 
+// AddProxies appends the proxies of a comma-separated list.
+func (o *Options) AddProxies(list string) {
+    for _, p := range strings.Split(list, ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            o.Proxies = append(o.Proxies, p)
+        }
+    }
+}
+
+// LoadProxiesFile appends one proxy URL per line; blank lines and "#" comments are skipped.
+func (o *Options) LoadProxiesFile(name string) error {
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return err
+    }
+    for _, line := range strings.Split(string(data), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        o.Proxies = append(o.Proxies, line)
+    }
+    return nil
+}
+
+// AddCookie appends "name=value[; name2=value2]" pairs to Cookies.
+func (o *Options) AddCookie(c string) {
+    c = strings.Trim(strings.TrimSpace(c), ";")
+    if c == "" {
+        return
+    }
+    if o.Cookies == "" {
+        o.Cookies = c
+        return
+    }
+    o.Cookies += "; " + c
+}
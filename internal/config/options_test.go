@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+func TestOriginFor(t *testing.T) {
+    tests := []struct {
+        entry string
+        ssl   bool
+        port  int
+        want  string
+    }{
+        {"example.com", false, 80, "http://example.com"},
+        {"example.com", false, 443, "https://example.com"},
+        {"example.com", true, 8080, "https://example.com:8080"},
+        {"example.com", false, 8080, "http://example.com:8080"},
+        {"example.com", true, 80, "http://example.com"},
+        {"example.com", false, 0, "http://example.com"},
+        {"example.com:80", true, 443, "http://example.com"},
+        {"example.com:443", false, 443, "https://example.com"},
+        {"example.com:8443", false, 443, "https://example.com:8443"},
+        {"example.com:9000", true, 443, "https://example.com:9000"},
+        {"::1", false, 80, "http://[::1]"},
+        {"::1", false, 8080, "http://[::1]:8080"},
+        {"[::1]", true, 443, "https://[::1]"},
+        {"[::1]:8443", false, 443, "https://[::1]:8443"},
+        {"[2001:db8::1]:80", false, 443, "http://[2001:db8::1]"},
+        {"https://example.com:8443/ignored/path", false, 80, "https://example.com:8443"},
+        {" 10.0.0.1 ", false, 443, "https://10.0.0.1"},
+    }
+    for _, tt := range tests {
+        o := &Options{Ssl: tt.ssl, Port: tt.port}
+        got, err := o.OriginFor(tt.entry)
+        if err != nil {
+            t.Errorf("OriginFor(%q): %v", tt.entry, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("OriginFor(%q) ssl=%v port=%d = %q, want %q", tt.entry, tt.ssl, tt.port, got, tt.want)
+        }
+    }
+    for _, bad := range []string{"", "   ", "://nohost"} {
+        if got, err := (&Options{}).OriginFor(bad); err == nil {
+            t.Errorf("OriginFor(%q) = %q, want error", bad, got)
+        }
+    }
+}
@@ -0,0 +1,47 @@
+package detect
+
+import (
+    "bytes"
+    "fmt"
+)
+
+// BodyDiff compares a response body against a reference body.
+type BodyDiff struct {
+    Size       int     // bytes in the body
+    RefSize    int     // bytes in the reference
+    Similarity float64 // share of lines the two have in common (Dice coefficient, 0..1)
+    Identical  bool
+}
+
+// CompareBodies measures how much of body and ref are the same, line by line. Line order
+// is ignored, so reordered or partly templated pages still compare as similar.
+func CompareBodies(body, ref []byte) BodyDiff {
+    d := BodyDiff{Size: len(body), RefSize: len(ref), Identical: bytes.Equal(body, ref)}
+    if d.Identical {
+        d.Similarity = 1
+        return d
+    }
+    a, b := bytes.Split(body, []byte("\n")), bytes.Split(ref, []byte("\n"))
+    counts := make(map[string]int, len(b))
+    for _, l := range b {
+        counts[string(bytes.TrimSpace(l))]++
+    }
+    common := 0
+    for _, l := range a {
+        k := string(bytes.TrimSpace(l))
+        if counts[k] > 0 {
+            counts[k]--
+            common++
+        }
+    }
+    d.Similarity = 2 * float64(common) / float64(len(a)+len(b))
+    return d
+}
+
+// String summarizes the comparison, e.g. "512 vs 1024 bytes, 37% similar".
+func (d BodyDiff) String() string {
+    if d.Identical {
+        return fmt.Sprintf("identical (%d bytes)", d.Size)
+    }
+    return fmt.Sprintf("%d vs %d bytes, %.0f%% similar", d.Size, d.RefSize, d.Similarity*100)
+}
@@ -0,0 +1,205 @@
+package detect
+
+import (
+    "sort"
+    "strings"
+)
+
+// volatileHeaders change between any two responses of the same backend and carry no
+// signal about which backend answered.
+var volatileHeaders = map[string]bool{
+    "date": true, "content-length": true, "transfer-encoding": true, "connection": true,
+    "keep-alive": true, "age": true, "expires": true, "last-modified": true, "etag": true,
+    "set-cookie": true, // compared by cookie name instead
+    "x-request-id": true, "x-correlation-id": true, "x-trace-id": true, "traceparent": true,
+    "x-amzn-requestid": true, "x-amz-cf-id": true, "x-amz-request-id": true, "cf-ray": true,
+    "server-timing": true,
+}
+
+// HeaderFingerprint describes the header set of a response: lower-cased names in
+// received order, the names as received (for casing), and the Set-Cookie names.
+type HeaderFingerprint struct {
+    Names   []string
+    Raw     map[string]string // lower-cased name -> name as received
+    Cookies []string
+    Ordered bool // order and casing are as received (raw transport)
+}
+
+// Fingerprint builds the header fingerprint of a response from its header names as
+// received, its Set-Cookie cookie names, and whether names are in received order and
+// casing (raw transport). Volatile headers are ignored.
+func Fingerprint(names, cookies []string, ordered bool) HeaderFingerprint {
+    fp := HeaderFingerprint{Raw: make(map[string]string), Ordered: ordered}
+    for _, name := range names {
+        key := strings.ToLower(name)
+        if volatileHeaders[key] {
+            continue
+        }
+        if _, seen := fp.Raw[key]; !seen {
+            fp.Names = append(fp.Names, key)
+            fp.Raw[key] = name
+        }
+    }
+    fp.Cookies = append(fp.Cookies, cookies...)
+    return fp
+}
+
+// HeaderDiff lists how a header set differs from every reference set.
+type HeaderDiff struct {
+    Added          []string // header names absent from all references
+    Removed        []string // header names present in all references but missing
+    CookiesAdded   []string // Set-Cookie names absent from all references
+    CookiesRemoved []string // Set-Cookie names present in all references but missing
+    Casing         []string // names whose casing differs from all references
+    OrderChanged   bool     // shared headers arrive in a different order than in all references
+}
+
+// Empty reports whether no difference was found.
+func (d HeaderDiff) Empty() bool {
+    return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.CookiesAdded) == 0 &&
+        len(d.CookiesRemoved) == 0 && len(d.Casing) == 0 && !d.OrderChanged
+}
+
+// DiffHeaders compares v against refs, keeping only differences that hold against every
+// reference, in the same spirit as DiffInt/DiffStr. Casing and order are only compared
+// when all fingerprints were captured in received order.
+func DiffHeaders(v HeaderFingerprint, refs ...HeaderFingerprint) HeaderDiff {
+    var d HeaderDiff
+    if len(refs) == 0 {
+        return d
+    }
+    d.Added = notInAny(v.Names, refs, func(r HeaderFingerprint) []string { return r.Names })
+    d.Removed = inAllMissing(v.Names, refs, func(r HeaderFingerprint) []string { return r.Names })
+    d.CookiesAdded = notInAny(v.Cookies, refs, func(r HeaderFingerprint) []string { return r.Cookies })
+    d.CookiesRemoved = inAllMissing(v.Cookies, refs, func(r HeaderFingerprint) []string { return r.Cookies })
+
+    ordered := v.Ordered
+    for _, r := range refs {
+        ordered = ordered && r.Ordered
+    }
+    if !ordered {
+        return d
+    }
+    for _, name := range v.Names {
+        differs := true
+        for _, r := range refs {
+            if raw, ok := r.Raw[name]; !ok || raw == v.Raw[name] {
+                differs = false
+                break
+            }
+        }
+        if differs {
+            d.Casing = append(d.Casing, v.Raw[name])
+        }
+    }
+    d.OrderChanged = true
+    for _, r := range refs {
+        if sameOrder(v.Names, r.Names) {
+            d.OrderChanged = false
+            break
+        }
+    }
+    return d
+}
+
+// notInAny returns the values of vals that occur in none of the references.
+func notInAny(vals []string, refs []HeaderFingerprint, get func(HeaderFingerprint) []string) []string {
+    var out []string
+    for _, n := range vals {
+        found := false
+        for _, r := range refs {
+            if contains(get(r), n) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            out = append(out, n)
+        }
+    }
+    return out
+}
+
+// inAllMissing returns the values present in every reference but absent from vals.
+func inAllMissing(vals []string, refs []HeaderFingerprint, get func(HeaderFingerprint) []string) []string {
+    var out []string
+    for _, n := range get(refs[0]) {
+        if contains(vals, n) {
+            continue
+        }
+        inAll := true
+        for _, r := range refs[1:] {
+            if !contains(get(r), n) {
+                inAll = false
+                break
+            }
+        }
+        if inAll {
+            out = append(out, n)
+        }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// sameOrder reports whether the names shared by a and b appear in the same relative order.
+func sameOrder(a, b []string) bool {
+    var sa, sb []string
+    for _, n := range a {
+        if contains(b, n) {
+            sa = append(sa, n)
+        }
+    }
+    for _, n := range b {
+        if contains(a, n) {
+            sb = append(sb, n)
+        }
+    }
+    for i := range sa {
+        if sa[i] != sb[i] {
+            return false
+        }
+    }
+    return true
+}
+
+func contains(list []string, v string) bool {
+    for _, e := range list {
+        if e == v {
+            return true
+        }
+    }
+    return false
+}
+
+// Changes lists the added and removed headers and cookies as short tokens: "+name" /
+// "-name" for headers, "+set-cookie:name" / "-set-cookie:name" for cookies. Casing and
+// order changes are not part of them; they are in Casing and OrderChanged (and String).
+func (d HeaderDiff) Changes() (added, removed []string) {
+    for _, n := range d.Added {
+        added = append(added, "+"+n)
+    }
+    for _, n := range d.CookiesAdded {
+        added = append(added, "+set-cookie:"+n)
+    }
+    for _, n := range d.Removed {
+        removed = append(removed, "-"+n)
+    }
+    for _, n := range d.CookiesRemoved {
+        removed = append(removed, "-set-cookie:"+n)
+    }
+    return added, removed
+}
+
+// String summarises the diff on one line, e.g. "+x-envoy-upstream -x-frame-options ~order".
+func (d HeaderDiff) String() string {
+    added, removed := d.Changes()
+    parts := append(added, removed...)
+    for _, n := range d.Casing {
+        parts = append(parts, "~casing:"+n)
+    }
+    if d.OrderChanged {
+        parts = append(parts, "~order")
+    }
+    return strings.Join(parts, " ")
+}
@@ -0,0 +1,94 @@
+package detect
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestFingerprintSkipsVolatileAndDuplicates(t *testing.T) {
+    fp := Fingerprint([]string{"Date", "X-Frame-Options", "content-length", "Set-Cookie", "x-frame-options", "Server"}, []string{"sid"}, true)
+    if want := []string{"x-frame-options", "server"}; !reflect.DeepEqual(fp.Names, want) {
+        t.Errorf("Names = %v, want %v", fp.Names, want)
+    }
+    if fp.Raw["x-frame-options"] != "X-Frame-Options" {
+        t.Errorf("Raw keeps the first casing, got %q", fp.Raw["x-frame-options"])
+    }
+    if !reflect.DeepEqual(fp.Cookies, []string{"sid"}) || !fp.Ordered {
+        t.Errorf("Cookies/Ordered = %v %v", fp.Cookies, fp.Ordered)
+    }
+}
+
+func TestDiffHeaders(t *testing.T) {
+    fp := func(ordered bool, cookies []string, names ...string) HeaderFingerprint {
+        return Fingerprint(names, cookies, ordered)
+    }
+    parent := fp(true, []string{"sid"}, "Server", "X-Frame-Options", "Content-Type")
+    nonexistent := fp(true, []string{"sid"}, "Server", "X-Frame-Options", "Content-Type", "X-Debug")
+    tests := []struct {
+        name    string
+        v       HeaderFingerprint
+        refs    []HeaderFingerprint
+        want    HeaderDiff
+        changes string
+    }{
+        {
+            name: "same set",
+            v:    fp(true, []string{"sid"}, "Server", "X-Frame-Options", "Content-Type"),
+            refs: []HeaderFingerprint{parent, nonexistent},
+        },
+        {
+            name:    "added and removed against all references",
+            v:       fp(true, nil, "Server", "Content-Type", "X-Envoy-Upstream"),
+            refs:    []HeaderFingerprint{parent, nonexistent},
+            want:    HeaderDiff{Added: []string{"x-envoy-upstream"}, Removed: []string{"x-frame-options"}, CookiesRemoved: []string{"sid"}},
+            changes: "+x-envoy-upstream -x-frame-options -set-cookie:sid",
+        },
+        {
+            name: "present in one reference is not added",
+            v:    fp(true, []string{"sid"}, "Server", "X-Frame-Options", "Content-Type", "X-Debug"),
+            refs: []HeaderFingerprint{parent, nonexistent},
+        },
+        {
+            name: "missing from one reference only is not removed",
+            v:    fp(true, []string{"sid"}, "Server", "X-Frame-Options", "Content-Type"),
+            refs: []HeaderFingerprint{nonexistent, parent},
+        },
+        {
+            name:    "new cookie",
+            v:       fp(true, []string{"sid", "backend"}, "Server", "X-Frame-Options", "Content-Type"),
+            refs:    []HeaderFingerprint{parent},
+            want:    HeaderDiff{CookiesAdded: []string{"backend"}},
+            changes: "+set-cookie:backend",
+        },
+        {
+            name:    "casing and order",
+            v:       fp(true, []string{"sid"}, "content-type", "Server", "X-Frame-Options"),
+            refs:    []HeaderFingerprint{parent, nonexistent},
+            want:    HeaderDiff{Casing: []string{"content-type"}, OrderChanged: true},
+            changes: "~casing:content-type ~order",
+        },
+        {
+            name: "casing and order ignored unless all ordered",
+            v:    fp(false, []string{"sid"}, "content-type", "Server", "X-Frame-Options"),
+            refs: []HeaderFingerprint{parent, nonexistent},
+        },
+        {
+            name: "no references",
+            v:    fp(true, nil, "X-A"),
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := DiffHeaders(tt.v, tt.refs...)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("DiffHeaders = %+v, want %+v", got, tt.want)
+            }
+            if got.Empty() != (tt.changes == "") {
+                t.Errorf("Empty() = %v", got.Empty())
+            }
+            if s := got.String(); s != tt.changes {
+                t.Errorf("String() = %q, want %q", s, tt.changes)
+            }
+        })
+    }
+}
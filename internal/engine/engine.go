@@ -3,6 +3,8 @@ package engine
 import (
     "bufio"
     "context"
+    "errors"
+    "fmt"
     "net/url"
     "os"
     "strings"
@@ -21,14 +23,34 @@ type Deps struct {
     Client   *httpx.Client
     Payloads *payload.Source
     Sink     output.Sink
+    Evidence *output.EvidenceStore // raw exchanges behind findings; nil when not kept
 }
 
 // Target represents a single URL to scan, split into base host URL and raw path.
 // BaseURL must be an absolute URL with scheme and host (e.g., https://example.com)
 // Path is the raw path to request (should start with "/").
+// Template is set when the target comes from a raw request file; modules should send
+// requests through Deps.Fetch so the template's method, headers and body are preserved.
 type Target struct {
-    BaseURL string
-    Path    string
+    BaseURL  string
+    Path     string
+    Template *httpx.Template
+}
+
+// Fetch requests rawPath for target t. Targets backed by a raw request template are
+// rendered with rawPath at the template marker; plain targets use a simple request.
+func (d Deps) Fetch(t Target, rawPath string) (*httpx.Response, error) {
+    return d.FetchWith(t, rawPath, nil)
+}
+
+// FetchWith is Fetch with per-request options applied on top of the target's template
+// (if any). ro may be nil.
+func (d Deps) FetchWith(t Target, rawPath string, ro *httpx.RequestOptions) (*httpx.Response, error) {
+    if t.Template != nil {
+        target, tro := t.Template.Request(rawPath)
+        return d.Client.Do(t.BaseURL, target, tro.Merge(ro))
+    }
+    return d.Client.Do(t.BaseURL, rawPath, ro)
 }
 
 // Module is a self-contained check (e.g., SCT, Host header, Smuggling).
@@ -57,6 +79,59 @@ type Preprocessor interface {
 type Engine struct {
     Deps    Deps
     Modules []Module
+
+    hostsMu sync.Mutex
+    hosts   map[string]*hostState
+
+    probeMu sync.Mutex
+    probed  map[string]*probeResult
+}
+
+// hostState is per-origin bookkeeping, kept separate so that one failing host
+// does not influence how the others are scanned.
+type hostState struct {
+    mu       sync.Mutex
+    failures int // consecutive baseline errors
+}
+
+// host returns the state for the given origin, creating it on first use.
+func (e *Engine) host(baseURL string) *hostState {
+    e.hostsMu.Lock()
+    defer e.hostsMu.Unlock()
+    if e.hosts == nil {
+        e.hosts = make(map[string]*hostState)
+    }
+    hs, ok := e.hosts[baseURL]
+    if !ok {
+        hs = &hostState{}
+        e.hosts[baseURL] = hs
+    }
+    return hs
+}
+
+// skip reports whether the host exceeded max consecutive failures (max <= 0 disables skipping).
+func (hs *hostState) skip(max int) bool {
+    hs.mu.Lock()
+    defer hs.mu.Unlock()
+    return max > 0 && hs.failures >= max
+}
+
+// record updates the consecutive failure counter after a baseline request and reports
+// whether this failure made the host reach max. A lost login session
+// (httpx.ErrSessionLost, e.g. while re-login backs off) says nothing about the host and
+// is not counted.
+func (hs *hostState) record(err error, max int) bool {
+    hs.mu.Lock()
+    defer hs.mu.Unlock()
+    switch {
+    case errors.Is(err, httpx.ErrSessionLost):
+    case err != nil:
+        hs.failures++
+        return max > 0 && hs.failures == max
+    default:
+        hs.failures = 0
+    }
+    return false
 }
 
 // Run streams targets one-by-one and reuses a single base response per target across modules.
@@ -70,19 +145,31 @@ func (e *Engine) Run(ctx context.Context) error {
     worker := func() {
         defer wg.Done()
         for t := range jobs {
-            select { case <-ctx.Done(): return; default: }
+            // keep draining after cancellation so the producer never blocks on a full queue
+            select { case <-ctx.Done(): continue; default: }
             p := t.Path
             if p != "" && !strings.HasPrefix(p, "/") {
                 p = "/" + p
             }
+            hs := e.host(t.BaseURL)
+            if hs.skip(e.Deps.Opts.MaxHostErrors) {
+                continue
+            }
             // Build baseline once per target
-            base, err := e.Deps.Client.Do(t.BaseURL, p)
+            base, err := e.Deps.Fetch(t, p)
+            if hs.record(err, e.Deps.Opts.MaxHostErrors) {
+                fmt.Printf("[!] skipping %s: %d consecutive baseline requests failed (last: %v)\n", t.BaseURL, e.Deps.Opts.MaxHostErrors, err)
+            }
             if err != nil {
                 // skip target on error
                 continue
             }
+            // baselines stay in the findings-only traffic buffer until the target is done
+            rec := e.Deps.Client.Recorder()
+            rec.Pin(base.ExchangeID)
             for _, m := range e.Modules {
-                mt := Target{BaseURL: t.BaseURL, Path: p}
+                mt := t
+                mt.Path = p
                 mbase := base
                 if pp, ok := m.(Preprocessor); ok {
                     if nt, nb, perr := pp.Preprocess(ctx, e.Deps, mt, base); perr == nil {
@@ -93,8 +180,11 @@ func (e *Engine) Run(ctx context.Context) error {
                         }
                     }
                 }
+                rec.Pin(mbase.ExchangeID)
                 _ = m.Process(ctx, e.Deps, mt, mbase)
+                rec.Unpin(mbase.ExchangeID)
             }
+            rec.Unpin(base.ExchangeID)
         }
     }
 
@@ -109,6 +199,16 @@ func (e *Engine) Run(ctx context.Context) error {
 // iterateTargets builds targets from the configured wordlist in a streaming manner
 // and calls fn for each target. It avoids storing all targets in memory.
 func (e *Engine) iterateTargets(ctx context.Context, fn func(Target) error) error {
+    if e.Deps.Opts.RequestsFile {
+        tpls, err := httpx.LoadTemplates(e.Deps.Opts.Wordlist)
+        if err != nil { return err }
+        for _, t := range interleave(tpls, e.Deps.Opts.Ssl) {
+            select { case <-ctx.Done(): return ctx.Err(); default: }
+            if err := fn(t); err != nil { _ = err }
+        }
+        return nil
+    }
+
     wl, err := os.Open(e.Deps.Opts.Wordlist)
     if err != nil { return err }
     defer wl.Close()
@@ -131,13 +231,68 @@ func (e *Engine) iterateTargets(ctx context.Context, fn func(Target) error) erro
         return sc.Err()
     }
 
-    base, err := e.Deps.Opts.BuildBaseURL()
+    entries := []string{e.Deps.Opts.Hostname}
+    if e.Deps.Opts.HostsFile {
+        entries, err = readHosts(e.Deps.Opts.Hostname)
+        if err != nil { return err }
+    }
+    bases, err := e.resolveOrigins(ctx, entries)
     if err != nil { return err }
+    // Path-major order: every host gets the current path before the next path is read,
+    // so consecutive jobs hit different origins and no single host is hammered.
     for sc.Scan() {
-        select { case <-ctx.Done(): return ctx.Err(); default: }
         p := strings.TrimSpace(sc.Text())
         if p == "" { continue }
-        if err := fn(Target{BaseURL: base, Path: p}); err != nil { _ = err }
+        for _, base := range bases {
+            select { case <-ctx.Done(): return ctx.Err(); default: }
+            if err := fn(Target{BaseURL: base, Path: p}); err != nil { _ = err }
+        }
     }
     return sc.Err()
 }
+
+// interleave turns request templates into targets ordered round-robin over their
+// origins (in order of first appearance), like the path-major order of wordlist scans,
+// so a directory of requests for several hosts does not hammer one host at a time.
+func interleave(tpls []*httpx.Template, ssl bool) []Target {
+    var origins []string
+    queues := map[string][]Target{}
+    for _, tpl := range tpls {
+        base := tpl.BaseURL(ssl)
+        if _, ok := queues[base]; !ok {
+            origins = append(origins, base)
+        }
+        queues[base] = append(queues[base], Target{BaseURL: base, Path: tpl.Path(), Template: tpl})
+    }
+    out := make([]Target, 0, len(tpls))
+    for len(out) < len(tpls) {
+        for _, base := range origins {
+            if q := queues[base]; len(q) > 0 {
+                out = append(out, q[0])
+                queues[base] = q[1:]
+            }
+        }
+    }
+    return out
+}
+
+// readHosts reads a host list (hostname, host:port or origin per line).
+// Blank lines and "#" comments are skipped.
+func readHosts(name string) ([]string, error) {
+    f, err := os.Open(name)
+    if err != nil { return nil, err }
+    defer f.Close()
+
+    var out []string
+    sc := bufio.NewScanner(f)
+    for sc.Scan() {
+        line := strings.TrimSpace(sc.Text())
+        if line == "" || strings.HasPrefix(line, "#") { continue }
+        out = append(out, line)
+    }
+    if err := sc.Err(); err != nil { return nil, err }
+    if len(out) == 0 {
+        return nil, fmt.Errorf("no hosts in %s", name)
+    }
+    return out, nil
+}
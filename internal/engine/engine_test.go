@@ -0,0 +1,74 @@
+package engine
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+
+    "pohek/internal/httpx"
+)
+
+func TestInterleave(t *testing.T) {
+    var tpls []*httpx.Template
+    for _, raw := range []string{
+        "GET /a1 HTTP/1.1\nHost: a\n",
+        "GET /a2 HTTP/1.1\nHost: a\n",
+        "GET /a3 HTTP/1.1\nHost: a\n",
+        "GET /b1 HTTP/1.1\nHost: b\n",
+        "GET /c1 HTTP/1.1\nHost: c:443\n",
+        "GET /b2 HTTP/1.1\nHost: b\n",
+    } {
+        tpl, err := httpx.ParseTemplate([]byte(raw))
+        if err != nil {
+            t.Fatal(err)
+        }
+        tpls = append(tpls, tpl)
+    }
+    want := []string{
+        "http://a /a1", "http://b /b1", "https://c:443 /c1",
+        "http://a /a2", "http://b /b2",
+        "http://a /a3",
+    }
+    got := interleave(tpls, false)
+    if len(got) != len(want) {
+        t.Fatalf("got %d targets, want %d", len(got), len(want))
+    }
+    for i, tg := range got {
+        if s := tg.BaseURL + " " + tg.Path; s != want[i] {
+            t.Errorf("target %d = %q, want %q", i, s, want[i])
+        }
+        if tg.Template == nil {
+            t.Errorf("target %d has no template", i)
+        }
+    }
+}
+
+func TestHostStateIgnoresLostSession(t *testing.T) {
+    hs := &hostState{}
+    lost := fmt.Errorf("session: %w", httpx.ErrSessionLost)
+    down := errors.New("connection refused")
+    steps := []struct {
+        err      error
+        disabled bool
+        skip     bool
+    }{
+        {down, false, false},
+        {lost, false, false},
+        {lost, false, false},
+        {lost, false, false}, // a backing-off login never disables the host
+        {down, false, false},
+        {nil, false, false}, // success resets the count
+        {down, false, false},
+        {down, false, false},
+        {down, true, true}, // the third consecutive failure disables it, once
+        {down, false, true},
+    }
+    for i, st := range steps {
+        if got := hs.record(st.err, 3); got != st.disabled {
+            t.Errorf("step %d: record = %v, want %v", i, got, st.disabled)
+        }
+        if got := hs.skip(3); got != st.skip {
+            t.Errorf("step %d: skip = %v, want %v", i, got, st.skip)
+        }
+    }
+}
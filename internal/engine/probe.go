@@ -0,0 +1,132 @@
+package engine
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/url"
+    "strings"
+    "sync"
+
+    "pohek/internal/config"
+)
+
+// resolveOrigins converts host entries to base URLs, dropping duplicates and entries
+// that cannot be parsed or probed. Bare hostnames are probed concurrently when
+// Opts.Probe is set; everything else goes through Options.OriginFor.
+func (e *Engine) resolveOrigins(ctx context.Context, entries []string) ([]string, error) {
+    threads := e.Deps.Opts.Threads
+    if threads <= 0 { threads = 1 }
+
+    resolved := make([]string, len(entries))
+    idx := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < threads; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range idx {
+                if base, err := e.origin(entries[i]); err == nil {
+                    resolved[i] = base
+                } else {
+                    fmt.Printf("[!] skipping host %s: %v\n", entries[i], err)
+                }
+            }
+        }()
+    }
+feed:
+    for i := range entries {
+        select {
+        case <-ctx.Done():
+            break feed
+        case idx <- i:
+        }
+    }
+    close(idx)
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return nil, err }
+
+    seen := make(map[string]bool)
+    out := make([]string, 0, len(resolved))
+    for _, b := range resolved {
+        if b == "" || seen[b] { continue }
+        seen[b] = true
+        out = append(out, b)
+    }
+    if len(out) == 0 {
+        return nil, fmt.Errorf("no usable hosts")
+    }
+    return out, nil
+}
+
+// origin returns the base URL for a host entry. Probing results are cached per
+// hostname, so the decision is made once for the whole scan.
+func (e *Engine) origin(entry string) (string, error) {
+    entry = strings.TrimSpace(entry)
+    if !e.Deps.Opts.Probe || strings.Contains(entry, "://") {
+        return e.Deps.Opts.OriginFor(entry)
+    }
+    if _, _, err := net.SplitHostPort(entry); err == nil {
+        return e.Deps.Opts.OriginFor(entry)
+    }
+
+    e.probeMu.Lock()
+    if e.probed == nil {
+        e.probed = make(map[string]*probeResult)
+    }
+    pr, ok := e.probed[entry]
+    if !ok {
+        pr = &probeResult{}
+        e.probed[entry] = pr
+    }
+    e.probeMu.Unlock()
+
+    // a bare IPv6 entry may come bracketed ("[::1]"); JoinOrigin adds the brackets
+    pr.once.Do(func() { pr.origin, pr.err = e.probe(strings.Trim(entry, "[]")) })
+    return pr.origin, pr.err
+}
+
+// probeResult caches the outcome of probing a single hostname.
+type probeResult struct {
+    once   sync.Once
+    origin string
+    err    error
+}
+
+// probe tries HTTPS and then HTTP on every configured port and returns the first origin
+// that answers. A plain-HTTP answer redirecting to https on the same host is followed
+// once, and the HTTPS origin is preferred when it answers too.
+func (e *Engine) probe(host string) (string, error) {
+    ports := e.Deps.Opts.ProbePorts
+    if len(ports) == 0 {
+        ports = []int{443, 80}
+    }
+    for _, port := range ports {
+        for _, ssl := range []bool{true, false} {
+            origin := config.JoinOrigin(host, fmt.Sprint(port), ssl)
+            status, location, err := e.Deps.Client.Probe(origin)
+            if err != nil {
+                continue
+            }
+            if !ssl && status >= 300 && status < 400 {
+                if up := upgradeOrigin(host, location); up != "" {
+                    if _, _, err := e.Deps.Client.Probe(up); err == nil {
+                        return up, nil
+                    }
+                }
+            }
+            return origin, nil
+        }
+    }
+    return "", fmt.Errorf("no HTTP(S) service on ports %v", ports)
+}
+
+// upgradeOrigin returns the https origin from a redirect Location if it points to the
+// same hostname, or "" if the redirect is not a scheme upgrade.
+func upgradeOrigin(host, location string) string {
+    u, err := url.Parse(location)
+    if err != nil || u.Scheme != "https" || !strings.EqualFold(u.Hostname(), host) {
+        return ""
+    }
+    return "https://" + u.Host
+}
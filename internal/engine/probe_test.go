@@ -0,0 +1,94 @@
+package engine
+
+import (
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strconv"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+    "pohek/internal/httpx"
+)
+
+func serverPort(t *testing.T, srv *httptest.Server) int {
+    t.Helper()
+    u, err := url.Parse(srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    p, _ := strconv.Atoi(u.Port())
+    return p
+}
+
+func probeEngine(t *testing.T, ports ...int) *Engine {
+    t.Helper()
+    opt := &config.Options{Probe: true, ProbePorts: ports, NoTLSValidation: true, Timeout: 5 * time.Second, Threads: 2}
+    c, err := httpx.New(opt)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return &Engine{Deps: Deps{Opts: opt, Client: c}}
+}
+
+func TestProbe(t *testing.T) {
+    plain := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+    defer plain.Close()
+    tls := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+    defer tls.Close()
+    tlsPort := serverPort(t, tls)
+    upgrade := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Redirect(w, r, "https://127.0.0.1:"+strconv.Itoa(tlsPort)+"/", http.StatusMovedPermanently)
+    }))
+    defer upgrade.Close()
+    elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Redirect(w, r, "https://other.example/", http.StatusFound)
+    }))
+    defer elsewhere.Close()
+    closed := httptest.NewServer(nil)
+    closedPort := serverPort(t, closed)
+    closed.Close()
+
+    tests := []struct {
+        name  string
+        ports []int
+        want  string
+    }{
+        {"http only", []int{serverPort(t, plain)}, plain.URL},
+        {"https only", []int{tlsPort}, tls.URL},
+        {"https preferred over http", []int{tlsPort, serverPort(t, plain)}, tls.URL},
+        {"closed port skipped", []int{closedPort, serverPort(t, plain)}, plain.URL},
+        {"http redirecting to https", []int{serverPort(t, upgrade)}, tls.URL},
+        {"redirect to another host is not an upgrade", []int{serverPort(t, elsewhere)}, elsewhere.URL},
+    }
+    for _, tt := range tests {
+        got, err := probeEngine(t, tt.ports...).origin("127.0.0.1")
+        if err != nil || got != tt.want {
+            t.Errorf("%s: origin = %q, %v; want %q", tt.name, got, err, tt.want)
+        }
+    }
+    if got, err := probeEngine(t, closedPort).origin("127.0.0.1"); err == nil {
+        t.Errorf("nothing listening: origin = %q, want error", got)
+    }
+}
+
+func TestProbeBracketedIPv6(t *testing.T) {
+    ln, err := net.Listen("tcp", "[::1]:0")
+    if err != nil {
+        t.Skip("no IPv6 loopback")
+    }
+    srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+    srv.Listener.Close()
+    srv.Listener = ln
+    srv.Start()
+    defer srv.Close()
+    port := serverPort(t, srv)
+    for _, entry := range []string{"[::1]", "::1"} {
+        got, err := probeEngine(t, port).origin(entry)
+        if want := "http://[::1]:" + strconv.Itoa(port); err != nil || got != want {
+            t.Errorf("origin(%q) = %q, %v; want %q", entry, got, err, want)
+        }
+    }
+}
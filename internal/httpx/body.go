@@ -0,0 +1,129 @@
+package httpx
+
+import (
+    "bufio"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "hash"
+    "io"
+    "net/http"
+    "strings"
+
+    "github.com/andybalholm/brotli"
+)
+
+// Body decoding modes for Options.Decode.
+const (
+    DecodeAuto = "auto" // decode gzip, deflate and br bodies
+    DecodeNone = "none" // keep bodies exactly as received
+)
+
+// DefaultMaxBody is the body prefix kept per response when Options.MaxBody is not set.
+const DefaultMaxBody = 1 << 20
+
+// bodyInfo is what readBody learned about a response body.
+type bodyInfo struct {
+    data      []byte
+    size      int64 // decoded size; only the kept prefix when truncated without hashing
+    truncated bool
+    sha256    string
+    encoding  string // Content-Encoding of the body as sent by the server
+    decoded   bool
+    decodeErr string
+}
+
+// readBody reads at most max decoded bytes of resp.Body (0 = no limit). When hashing is
+// enabled the rest of the body is streamed through the hash and discarded, so the hash
+// covers the full body while only a prefix is kept in memory; otherwise reading stops at
+// the limit and the caller closes the body, which drops the connection.
+func readBody(resp *http.Response, max int64, hashing bool, decode string) bodyInfo {
+    var info bodyInfo
+    var r io.Reader = resp.Body
+    if ce := strings.TrimSpace(resp.Header.Get("Content-Encoding")); ce != "" {
+        info.encoding = ce
+        if decode != DecodeNone {
+            dr, err := decoder(r, ce)
+            r = dr
+            if err != nil {
+                info.decodeErr = err.Error()
+            } else {
+                info.decoded = true
+            }
+        }
+    }
+
+    var h hash.Hash
+    if hashing {
+        h = sha256.New()
+        r = io.TeeReader(r, h)
+    }
+    var err error
+    if max > 0 {
+        // one extra byte tells whether the body was longer than the limit
+        info.data, err = io.ReadAll(io.LimitReader(r, max+1))
+        if int64(len(info.data)) > max {
+            info.data, info.truncated = info.data[:max], true
+        }
+        info.size = int64(len(info.data))
+        if info.truncated && hashing && err == nil {
+            var n int64
+            n, err = io.Copy(io.Discard, r)
+            info.size += 1 + n // the extra byte read past the limit
+        }
+    } else {
+        info.data, err = io.ReadAll(r)
+        info.size = int64(len(info.data))
+    }
+    if err != nil && info.decoded && info.decodeErr == "" {
+        info.decodeErr = err.Error()
+    }
+    if h != nil && err == nil {
+        info.sha256 = hex.EncodeToString(h.Sum(nil))
+    }
+    return info
+}
+
+// decoder wraps r to undo the Content-Encoding ce. Codings are listed in the order they
+// were applied, so they are removed from last to first. On error the returned reader
+// yields the body with the codings undone so far, so unknown or mislabelled codings leave
+// the body as received.
+func decoder(r io.Reader, ce string) (io.Reader, error) {
+    codings := strings.Split(strings.ToLower(ce), ",")
+    for i := len(codings) - 1; i >= 0; i-- {
+        switch c := strings.TrimSpace(codings[i]); c {
+        case "identity", "":
+        case "gzip", "x-gzip":
+            // check the magic first so a mislabelled body is kept intact
+            br := bufio.NewReader(r)
+            if head, err := br.Peek(2); err != nil || head[0] != 0x1f || head[1] != 0x8b {
+                return br, fmt.Errorf("gzip: not gzip data")
+            }
+            zr, err := gzip.NewReader(br)
+            if err != nil {
+                return br, fmt.Errorf("gzip: %w", err)
+            }
+            r = zr
+        case "deflate":
+            // "deflate" is meant to be zlib-wrapped, but raw deflate is common in the wild
+            br := bufio.NewReader(r)
+            if head, err := br.Peek(2); err == nil && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+                zr, err := zlib.NewReader(br)
+                if err != nil {
+                    return br, fmt.Errorf("deflate: %w", err)
+                }
+                r = zr
+            } else {
+                r = flate.NewReader(br)
+            }
+        case "br":
+            r = brotli.NewReader(r)
+        default:
+            return r, fmt.Errorf("unsupported content encoding %q", c)
+        }
+    }
+    return r, nil
+}
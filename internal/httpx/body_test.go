@@ -0,0 +1,129 @@
+package httpx
+
+import (
+    "bytes"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "net/http"
+    "strings"
+    "testing"
+
+    "github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, coding string, data []byte) []byte {
+    t.Helper()
+    var b bytes.Buffer
+    var w io.WriteCloser
+    switch coding {
+    case "gzip":
+        w = gzip.NewWriter(&b)
+    case "zlib":
+        w = zlib.NewWriter(&b)
+    case "flate":
+        w, _ = flate.NewWriter(&b, flate.DefaultCompression)
+    case "br":
+        w = brotli.NewWriter(&b)
+    }
+    w.Write(data)
+    w.Close()
+    return b.Bytes()
+}
+
+func bodyResponse(encoding string, body []byte) *http.Response {
+    h := http.Header{}
+    if encoding != "" {
+        h.Set("Content-Encoding", encoding)
+    }
+    return &http.Response{Header: h, Body: io.NopCloser(bytes.NewReader(body))}
+}
+
+func TestReadBodyDecoding(t *testing.T) {
+    plain := []byte(strings.Repeat("hello pohek ", 100))
+    tests := []struct {
+        name      string
+        encoding  string
+        body      []byte
+        decode    string
+        want      []byte
+        decoded   bool
+        decodeErr bool
+    }{
+        {"identity", "", plain, DecodeAuto, plain, false, false},
+        {"gzip", "gzip", compress(t, "gzip", plain), DecodeAuto, plain, true, false},
+        {"x-gzip upper case", "X-GZIP", compress(t, "gzip", plain), DecodeAuto, plain, true, false},
+        {"zlib deflate", "deflate", compress(t, "zlib", plain), DecodeAuto, plain, true, false},
+        {"raw deflate", "deflate", compress(t, "flate", plain), DecodeAuto, plain, true, false},
+        {"brotli", "br", compress(t, "br", plain), DecodeAuto, plain, true, false},
+        {"stacked codings", "gzip, br", compress(t, "br", compress(t, "gzip", plain)), DecodeAuto, plain, true, false},
+        {"mislabelled gzip kept as received", "gzip", plain, DecodeAuto, plain, false, true},
+        {"unknown coding kept", "zstd", []byte("zzz"), DecodeAuto, []byte("zzz"), false, true},
+        {"decode none", "gzip", compress(t, "gzip", plain), DecodeNone, compress(t, "gzip", plain), false, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            info := readBody(bodyResponse(tt.encoding, tt.body), 0, false, tt.decode)
+            if !bytes.Equal(info.data, tt.want) {
+                t.Errorf("data = %q..., want %q...", head(info.data), head(tt.want))
+            }
+            if info.decoded != tt.decoded || (info.decodeErr != "") != tt.decodeErr {
+                t.Errorf("decoded=%v decodeErr=%q, want decoded=%v error=%v", info.decoded, info.decodeErr, tt.decoded, tt.decodeErr)
+            }
+            if info.encoding != tt.encoding {
+                t.Errorf("encoding = %q, want %q", info.encoding, tt.encoding)
+            }
+            if info.size != int64(len(tt.want)) || info.truncated {
+                t.Errorf("size=%d truncated=%v", info.size, info.truncated)
+            }
+        })
+    }
+}
+
+func TestReadBodyTruncation(t *testing.T) {
+    plain := []byte(strings.Repeat("0123456789", 10)) // 100 bytes
+    sum := sha256.Sum256(plain)
+    full := hex.EncodeToString(sum[:])
+    tests := []struct {
+        name      string
+        max       int64
+        hashing   bool
+        encoding  string
+        wantLen   int
+        size      int64
+        truncated bool
+        sha       string
+    }{
+        {"under limit", 200, false, "", 100, 100, false, ""},
+        {"exactly at limit", 100, false, "", 100, 100, false, ""},
+        {"truncated without hash", 10, false, "", 10, 10, true, ""},
+        {"truncated with hash covers full body", 10, true, "", 10, 100, true, full},
+        {"no limit with hash", 0, true, "", 100, 100, false, full},
+        {"limit applies to decoded bytes", 30, true, "gzip", 30, 100, true, full},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            body := plain
+            if tt.encoding != "" {
+                body = compress(t, "gzip", plain)
+            }
+            info := readBody(bodyResponse(tt.encoding, body), tt.max, tt.hashing, DecodeAuto)
+            if len(info.data) != tt.wantLen || !bytes.Equal(info.data, plain[:tt.wantLen]) {
+                t.Errorf("kept %d bytes %q, want the first %d", len(info.data), head(info.data), tt.wantLen)
+            }
+            if info.size != tt.size || info.truncated != tt.truncated || info.sha256 != tt.sha {
+                t.Errorf("size=%d truncated=%v sha=%q, want %d %v %q", info.size, info.truncated, info.sha256, tt.size, tt.truncated, tt.sha)
+            }
+        })
+    }
+}
+
+func head(b []byte) []byte {
+    if len(b) > 20 {
+        return b[:20]
+    }
+    return b
+}
@@ -1,35 +1,51 @@
 package httpx
 
 import (
-    "crypto/tls"
+    "bytes"
+    "context"
+    "encoding/base64"
+    "errors"
     "fmt"
+    "io"
     "io/ioutil"
+    "net"
     "net/http"
+    "net/http/httptrace"
     "net/url"
+    "sort"
+    "strings"
     "time"
 
     "pohek/internal/config"
 )
 
-// Response is a minimal, serializable representation of an HTTP response
-// used by scanner and detectors. It intentionally avoids exposing net/http internals.
-type Response struct {
-    Server      string
-    ContentType string
-    StatusCode  int
-    Body        []byte
-    RequestURL  string
-}
-
 // Client wraps net/http.Client and request building logic (headers, cookies, method, redirects, TLS).
 // It also supports raw path injection using Request.URL.Opaque for traversal testing.
 type Client struct {
-    hc        *http.Client
+    tr        http.RoundTripper // ProtoAuto
+    tr11      http.RoundTripper // ProtoHTTP11
+    h2        http.RoundTripper // ProtoH2
+    h2c       http.RoundTripper // ProtoH2C
+    proto     string
+    follow    bool
+    timeout   time.Duration
+    raw       *rawTransport
+    rawMode   string
+    proxy     func(*http.Request) (*url.URL, error)
+    pool      *proxyPool // configured proxy list; nil when using the environment or no proxy
     userAgent string
     headers   map[string]string
     cookies   string
+    jar       *cookieJar
+    auth      string // Authorization value from Basic/Bearer options
+    redactor  *Redactor // secret headers hidden in findings; nil with NoRedact
+    session   *Session
     method    string
     delay     bool
+    rec       *Recorder // traffic recorder; nil when disabled
+    maxBody   int64 // decoded body bytes kept per response, 0 = no limit
+    hashBody  bool
+    decode    string
 }
 
 // New creates a new HTTP client from config.Options.
@@ -38,20 +54,18 @@ func New(opt *config.Options) (*Client, error) {
         return nil, fmt.Errorf("options is nil")
     }
 
+    // Proxies: an explicit list wins, otherwise the environment (HTTP_PROXY etc.) applies
+    var pool *proxyPool
     proxyFunc := http.ProxyFromEnvironment
-    if opt.Proxy {
-        if opt.ProxyUrl != "" {
-            pu, err := url.Parse(opt.ProxyUrl)
-            if err == nil {
-                proxyFunc = http.ProxyURL(pu)
-            }
+    if len(opt.Proxies) > 0 {
+        var err error
+        if pool, err = newProxyPool(opt.Proxies, opt.ProxyRotate, opt.ProxyAuth); err != nil {
+            return nil, fmt.Errorf("proxy: %w", err)
+        }
+        proxyFunc = nil
+        if !pool.socks {
+            proxyFunc = pool.httpProxy
         }
-    }
-
-    // Configure redirect policy
-    var redirectFunc func(req *http.Request, via []*http.Request) error
-    if !opt.FollowRedirect {
-        redirectFunc = func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
     }
 
     // Clone default transport and adjust knobs
@@ -59,77 +73,537 @@ func New(opt *config.Options) (*Client, error) {
     tr.MaxIdleConns = 100
     tr.MaxConnsPerHost = 100
     tr.MaxIdleConnsPerHost = 100
-    tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: opt.NoTLSValidation}
+    tlsConf, err := tlsConfig(opt)
+    if err != nil {
+        return nil, err
+    }
+    tr.TLSClientConfig = tlsConf
     tr.Proxy = proxyFunc
+    if pool != nil && pool.socks {
+        forward := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+        tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+            return pool.dial(ctx, forward, network, addr)
+        }
+    }
+    if len(opt.Resolve) > 0 {
+        overrides, err := parseResolve(opt.Resolve)
+        if err != nil {
+            return nil, err
+        }
+        if proxyFunc != nil {
+            if err := checkResolveProxy(overrides, proxyFunc); err != nil {
+                return nil, err
+            }
+        }
+        tr.DialContext = resolveDial(overrides, tr.DialContext)
+    }
+    // net/http must neither add Accept-Encoding nor decode on its own: bodies are decoded
+    // in readBody for both transports, and a mislabelled body is then kept as received
+    tr.DisableCompression = true
 
+    raw := newRawTransport(tr.TLSClientConfig, proxyFunc, tr.DialContext, opt.Timeout)
     c := &Client{
-        hc: &http.Client{
-            Timeout:       opt.Timeout,
-            CheckRedirect: redirectFunc,
-            Transport:     tr,
-        },
+        tr:        tr,
+        tr11:      http11Transport(tr),
+        h2:        h2Transport(raw, false),
+        h2c:       h2Transport(raw, true),
+        proto:     opt.Protocol,
+        follow:    opt.FollowRedirect,
+        timeout:   opt.Timeout,
+        raw:       raw,
+        rawMode:   opt.RawHTTP,
+        proxy:     proxyFunc,
+        pool:      pool,
         userAgent: opt.UserAgent,
         headers:   opt.Headers,
         cookies:   opt.Cookies,
         method:    opt.Method,
+        maxBody:   opt.MaxBody,
+        hashBody:  opt.HashBody,
+        decode:    opt.Decode,
     }
+    c.tr = absoluteForm{t: tr, proxied: c.forwardProxied}
+    c.tr11 = absoluteForm{t: c.tr11, proxied: c.forwardProxied}
     if c.method == "" {
         c.method = http.MethodGet
     }
+    switch {
+    case c.maxBody == 0:
+        c.maxBody = DefaultMaxBody
+    case c.maxBody < 0:
+        c.maxBody = 0
+    }
+    switch c.decode {
+    case "":
+        c.decode = DecodeAuto
+    case DecodeAuto, DecodeNone:
+    default:
+        return nil, fmt.Errorf("invalid decode mode %q (want %s or %s)", c.decode, DecodeAuto, DecodeNone)
+    }
+    if opt.CookieJar != "" {
+        jar, err := loadCookieJar(opt.CookieJar)
+        if err != nil {
+            return nil, fmt.Errorf("cookie jar: %w", err)
+        }
+        c.jar = jar
+    }
+    switch {
+    case opt.BearerToken != "" && opt.BasicAuth != "":
+        return nil, fmt.Errorf("basic auth and bearer token are mutually exclusive")
+    case opt.BearerToken != "":
+        c.auth = "Bearer " + opt.BearerToken
+    case opt.BasicAuth != "":
+        user, pass, ok := strings.Cut(opt.BasicAuth, ":")
+        if !ok {
+            return nil, fmt.Errorf("basic auth must be user:password")
+        }
+        c.auth = "Basic " + basicAuth(user, pass)
+    }
+    if !opt.NoRedact {
+        c.redactor = NewRedactor(opt.TrafficRedact)
+    }
+    if opt.SessionFile != "" {
+        sess, err := LoadSession(opt.SessionFile, opt.Ssl)
+        if err != nil {
+            return nil, fmt.Errorf("session: %w", err)
+        }
+        sess.client = c
+        c.session = sess
+        // headers filled from the login response carry session secrets too
+        for _, rule := range sess.recipe.Extract {
+            c.redactor.Add(rule.Header)
+        }
+    }
+    if err := CheckProto(c.proto); err != nil {
+        return nil, err
+    }
+    switch c.rawMode {
+    case "":
+        c.rawMode = RawAuto
+    case RawAuto, RawAlways, RawNever:
+    default:
+        return nil, fmt.Errorf("invalid raw transport mode %q (want %s, %s or %s)", c.rawMode, RawAuto, RawAlways, RawNever)
+    }
+    // the traffic file is created last, so that a rejected option leaves nothing behind
+    if opt.TrafficFile != "" {
+        rec, err := NewRecorder(opt.TrafficFile, opt.TrafficMode, opt.TrafficMaxBody, opt.TrafficRotate, !opt.TrafficNoRedact, opt.TrafficRedact)
+        if err != nil {
+            return nil, fmt.Errorf("traffic log: %w", err)
+        }
+        c.rec = rec
+    }
     return c, nil
 }
 
-// SetRedirects toggles following redirects at runtime.
-func (c *Client) SetRedirects(follow bool) {
-    var redirectFunc func(req *http.Request, via []*http.Request) error
-    if !follow {
-        redirectFunc = func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
-    }
-    c.hc.CheckRedirect = redirectFunc
-}
+// Recorder returns the traffic recorder, or nil when traffic is not recorded.
+func (c *Client) Recorder() *Recorder { return c.rec }
+
+// Redactor returns the redaction applied to what findings show of requests and
+// responses, or nil when secrets are kept (Options.NoRedact).
+func (c *Client) Redactor() *Redactor { return c.redactor }
+
+// Close flushes and closes the traffic log, if any.
+func (c *Client) Close() error { return c.rec.Close() }
 
 // AddDelay enables small delays between requests (used by anti-ban strategies).
 func (c *Client) AddDelay() { c.delay = true }
 
-// Do issues a request to baseURL with the provided raw path inserted as Request.URL.Opaque.
-// baseURL must be a valid absolute URL without a path (scheme://host[:port]).
-func (c *Client) Do(baseURL string, rawPath string) (*Response, error) {
-    req, err := http.NewRequest(c.method, baseURL, nil)
+// Do issues a request to baseURL with the provided raw path sent verbatim as the request
+// target (via Request.URL.Opaque on the net/http transport). baseURL must be a valid
+// absolute URL without a path (scheme://host[:port]). ro may be nil to use client defaults.
+// With a session configured, a response matching the logout markers is checked against
+// the session's check request; when the session is gone it is renewed and the request
+// retried once with the new credentials, and when it cannot be renewed an error wrapping
+// ErrSessionLost is returned instead of the logged-out response.
+func (c *Client) Do(baseURL string, rawPath string, ro *RequestOptions) (*Response, error) {
+    if c.session == nil {
+        return c.do(baseURL, rawPath, ro, nil)
+    }
+    creds, err := c.session.current()
+    if err != nil {
+        return nil, fmt.Errorf("session: %w", err)
+    }
+    resp, err := c.do(baseURL, rawPath, ro, creds)
+    if err != nil || !c.session.loggedOut(resp) {
+        return resp, err
+    }
+    fresh, err := c.session.recover(creds.gen)
+    if err != nil {
+        return nil, fmt.Errorf("session: %w", err)
+    }
+    if fresh == nil {
+        return resp, nil // the session is fine; the logout marker is the target's own answer
+    }
+    return c.do(baseURL, rawPath, ro, fresh)
+}
+
+// do sends a single request, applying session credentials when creds is non-nil, and
+// hands the exchange to the traffic recorder if one is configured.
+func (c *Client) do(baseURL string, rawPath string, ro *RequestOptions, creds *credentials) (*Response, error) {
+    if ro == nil {
+        ro = &RequestOptions{}
+    }
+    if c.rec == nil {
+        return c.perform(baseURL, rawPath, ro, creds)
+    }
+    id, start := c.rec.next(), time.Now()
+    resp, err := c.perform(baseURL, rawPath, ro, creds)
+    if resp != nil {
+        resp.ExchangeID = id
+    }
+    method := ro.Method
+    if method == "" {
+        method = c.method
+    }
+    c.rec.record(id, start, baseURL+rawPath, method, resp, err)
+    return resp, err
+}
+
+// perform builds and sends one request over the transport selected by protocol and raw mode.
+func (c *Client) perform(baseURL string, rawPath string, ro *RequestOptions, creds *credentials) (*Response, error) {
+    method := ro.Method
+    if method == "" {
+        method = c.method
+    }
+    follow := c.follow
+    switch ro.Redirects {
+    case RedirectFollow:
+        follow = true
+    case RedirectNone:
+        follow = false
+    }
+    timeout := c.timeout
+    if ro.Timeout > 0 {
+        timeout = ro.Timeout
+    }
+    mode := c.rawMode
+    if ro.Raw != "" {
+        mode = ro.Raw
+    }
+    proto := c.proto
+    if ro.Proto != "" {
+        proto = ro.Proto
+    }
+    if err := CheckProto(proto); err != nil {
+        return nil, err
+    }
+
+    headers, host := takeHost(c.requestHeaders(baseURL, rawPath, ro.Headers, creds))
+    if host == "" {
+        host = ro.Host
+    }
+    switch {
+    case proto == ProtoHTTP10:
+        // net/http cannot write HTTP/1.0 requests
+        return c.sendRaw(baseURL, method, rawPath, host, headers, ro.Body, timeout, "HTTP/1.0")
+    case proto == ProtoH2 || proto == ProtoH2C:
+        // HTTP/2 framing is left to x/net/http2; the raw transport only speaks HTTP/1.x
+    case mode == RawAlways || (mode == RawAuto && needsRaw(rawPath)):
+        return c.sendRaw(baseURL, method, rawPath, host, headers, ro.Body, timeout, "HTTP/1.1")
+    }
+
+    var rd io.Reader
+    if ro.Body != nil {
+        rd = bytes.NewReader(ro.Body)
+    }
+    req, err := http.NewRequest(method, baseURL, rd)
     if err != nil {
         return nil, err
     }
     // Use opaque to avoid path normalization. Keep raw traversal sequences intact.
     req.URL.Opaque = rawPath
+    if host != "" {
+        req.Host = host
+    }
+    for _, h := range headers {
+        name := h.Name
+        if strings.EqualFold(name, "User-Agent") {
+            // net/http adds its own User-Agent unless the canonical key is present
+            name = "User-Agent"
+        }
+        req.Header[name] = append(req.Header[name], h.Value)
+    }
+    tr, err := c.roundTripper(proto, req.URL.Scheme)
+    if err != nil {
+        return nil, err
+    }
+    return c.send(tr, follow, timeout, req, ro.Body)
+}
+
+// httpClient returns a per-request net/http client sharing the pooled transport tr, so that
+// redirect policy and timeout never have to be changed on shared state. Followed
+// redirects are appended to hops when it is non-nil.
+func (c *Client) httpClient(tr http.RoundTripper, follow bool, timeout time.Duration, hops *[]Hop) *http.Client {
+    hc := &http.Client{Transport: tr, Timeout: timeout}
+    hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+        if !follow {
+            return http.ErrUseLastResponse
+        }
+        if len(via) >= 10 {
+            return errors.New("stopped after 10 redirects")
+        }
+        if hops != nil && req.Response != nil {
+            *hops = append(*hops, Hop{
+                URL:      requestURL(via[len(via)-1].URL),
+                Status:   req.Response.StatusCode,
+                Location: req.Response.Header.Get("Location"),
+            })
+        }
+        return nil
+    }
+    return hc
+}
+
+// requestHeaders returns the ordered header list for a request to baseURL and target:
+// User-Agent, Authorization, Cookie (configured cookies plus matching cookie-jar entries)
+// and configured headers, then session credentials, with extra headers replacing
+// same-named defaults in place.
+func (c *Client) requestHeaders(baseURL, target string, extra []Header, creds *credentials) []Header {
+    ua := c.userAgent
+    if ua == "" {
+        ua = "Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/78.0"
+    }
+    out := []Header{{Name: "User-Agent", Value: ua}}
+    if c.auth != "" {
+        out = append(out, Header{Name: "Authorization", Value: c.auth})
+    }
+    if cookie := c.cookieHeader(baseURL, target); cookie != "" {
+        out = append(out, Header{Name: "Cookie", Value: cookie})
+    }
+    names := make([]string, 0, len(c.headers))
+    for k := range c.headers {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+    for _, k := range names {
+        out = setHeader(out, Header{Name: k, Value: c.headers[k]})
+    }
+    if creds != nil {
+        for _, h := range creds.headers {
+            out = setHeader(out, h)
+        }
+        if len(creds.cookies) > 0 {
+            out = setHeader(out, Header{Name: "Cookie", Value: mergeCookies(headerValue(out, "Cookie"), creds.cookies)})
+        }
+    }
+    seen := make(map[string]bool)
+    for _, h := range extra {
+        key := strings.ToLower(h.Name)
+        if !seen[key] {
+            // first occurrence replaces defaults; repeated extra headers are all kept
+            out = setHeader(out, h)
+            seen[key] = true
+            continue
+        }
+        out = append(out, h)
+    }
+    return out
+}
+
+// takeHost removes Host from hs and returns the last value given, which overrides the
+// request's host: net/http ignores a Host entry in Request.Header, and the raw transport
+// writes its own Host line.
+func takeHost(hs []Header) ([]Header, string) {
+    var host string
+    out := hs[:0:0]
+    for _, h := range hs {
+        if strings.EqualFold(h.Name, "Host") {
+            host = h.Value
+            continue
+        }
+        out = append(out, h)
+    }
+    return out, host
+}
+
+// cookieHeader joins the configured cookies with the cookie-jar entries scoped to the request.
+func (c *Client) cookieHeader(baseURL, target string) string {
+    var fromJar string
+    if c.jar != nil {
+        if u, err := url.Parse(baseURL); err == nil {
+            fromJar = c.jar.header(u.Scheme, u.Hostname(), target)
+        }
+    }
+    switch {
+    case c.cookies == "":
+        return fromJar
+    case fromJar == "":
+        return c.cookies
+    }
+    return c.cookies + "; " + fromJar
+}
+
+// mergeCookies overrides same-named pairs of a Cookie header value and appends the rest.
+func mergeCookies(base string, over []Header) string {
+    var pairs []Header
+    for _, p := range strings.Split(base, ";") {
+        if name, value, ok := strings.Cut(strings.TrimSpace(p), "="); ok {
+            pairs = append(pairs, Header{Name: name, Value: value})
+        }
+    }
+    for _, o := range over {
+        replaced := false
+        for i := range pairs {
+            if pairs[i].Name == o.Name {
+                pairs[i].Value = o.Value
+                replaced = true
+            }
+        }
+        if !replaced {
+            pairs = append(pairs, o)
+        }
+    }
+    parts := make([]string, len(pairs))
+    for i, p := range pairs {
+        parts[i] = p.Name + "=" + p.Value
+    }
+    return strings.Join(parts, "; ")
+}
+
+func headerValue(list []Header, name string) string {
+    for _, h := range list {
+        if strings.EqualFold(h.Name, name) {
+            return h.Value
+        }
+    }
+    return ""
+}
+
+// setHeader replaces the first header named like h (case-insensitively) and drops any
+// further ones, or appends h if there is none.
+func setHeader(list []Header, h Header) []Header {
+    out := list[:0]
+    replaced := false
+    for _, e := range list {
+        if strings.EqualFold(e.Name, h.Name) {
+            if !replaced {
+                out = append(out, h)
+                replaced = true
+            }
+            continue
+        }
+        out = append(out, e)
+    }
+    if !replaced {
+        out = append(out, h)
+    }
+    return out
+}
 
-    if c.cookies != "" {
-        req.Header.Set("Cookie", c.cookies)
+// sendRaw sends the request over the raw socket transport, byte-for-byte, as proto
+// ("HTTP/1.0" or "HTTP/1.1").
+func (c *Client) sendRaw(baseURL, method, target, host string, headers []Header, body []byte, timeout time.Duration, proto string) (*Response, error) {
+    u, err := url.Parse(baseURL)
+    if err != nil {
+        return nil, err
+    }
+    addr := u.Host
+    if u.Port() == "" {
+        port := "80"
+        if u.Scheme == "https" {
+            port = "443"
+        }
+        addr = net.JoinHostPort(u.Hostname(), port)
+    }
+    if host == "" {
+        host = u.Host
     }
-    if c.userAgent != "" {
-        req.Header.Set("User-Agent", c.userAgent)
-    } else {
-        req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/78.0")
+    r := &rawRequest{
+        Scheme:  u.Scheme,
+        Addr:    addr,
+        Method:  method,
+        Target:  target,
+        Proto:   proto,
+        Host:    host,
+        Headers: headers,
+        Body:    body,
     }
-    for k, v := range c.headers {
-        req.Header.Set(k, v)
+    ctx := context.Background()
+    if timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
     }
+    ex := &exchange{url: u.Scheme + "://" + u.Host + target, sent: r.sent(), start: time.Now()}
+    resp, rawHeaders, err := c.raw.roundTrip(ctx, r)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    ex.rawHeaders = rawHeaders
+    return c.finish(resp, ex), nil
+}
 
-    resp, err := c.hc.Do(req)
+// forwardProxied reports whether req goes through an HTTP forward proxy, which receives
+// plain-HTTP requests in absolute-form. It does not advance proxy rotation.
+func (c *Client) forwardProxied(req *http.Request) bool {
+    switch {
+    case c.pool != nil:
+        return !c.pool.socks
+    case c.proxy != nil:
+        pu, err := c.proxy(req)
+        return err == nil && pu != nil
+    }
+    return false
+}
+
+// Probe sends GET / to origin without following redirects and returns the status code
+// and Location header. It only tells whether an origin speaks HTTP; the body is discarded.
+func (c *Client) Probe(origin string) (int, string, error) {
+    req, err := http.NewRequest(http.MethodGet, origin+"/", nil)
+    if err != nil {
+        return 0, "", err
+    }
+    headers, host := takeHost(c.requestHeaders(origin, "/", nil, nil))
+    for _, h := range headers {
+        req.Header.Set(h.Name, h.Value)
+    }
+    if host != "" {
+        req.Host = host
+    }
+    resp, err := c.httpClient(c.tr, false, c.timeout, nil).Do(req)
+    if err != nil {
+        return 0, "", err
+    }
+    defer resp.Body.Close()
+    _, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+    return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+
+// send performs req and records what was written on the wire. reqBody is the request
+// body as sent (nil for bodiless requests); it is only used for the SentRequest record.
+func (c *Client) send(tr http.RoundTripper, follow bool, timeout time.Duration, req *http.Request, reqBody []byte) (*Response, error) {
+    rec := &wireRecorder{}
+    req = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.trace()))
+    ex := &exchange{start: time.Now()}
+    resp, err := c.httpClient(tr, follow, timeout, &ex.redirects).Do(req)
     if err != nil {
         return nil, err
     }
     defer resp.Body.Close()
-    body, _ := ioutil.ReadAll(resp.Body)
 
-    out := &Response{
-        Server:      resp.Header.Get("Server"),
-        ContentType: resp.Header.Get("Content-Type"),
-        StatusCode:  resp.StatusCode,
-        Body:        body,
-        RequestURL:  resp.Request.URL.String(),
+    proto := "HTTP/1.1"
+    if resp.ProtoMajor == 2 && len(ex.redirects) == 0 {
+        proto = "HTTP/2.0"
     }
+    // HTTP/2 transports tunnel through proxies, so only HTTP/1.x can be in absolute-form
+    proxied := proto != "HTTP/2.0" && c.forwardProxied(req)
+    ex.url = requestURL(resp.Request.URL)
+    ex.sent = rec.sent(req, reqBody, proto, proxied)
+    return c.finish(resp, ex), nil
+}
+
+// finish reads the response body within the configured limit and builds the Response
+// shared by both transports.
+func (c *Client) finish(resp *http.Response, ex *exchange) *Response {
+    out := newResponse(resp, ex, readBody(resp, c.maxBody, c.hashBody, c.decode))
     if c.delay {
         time.Sleep(1 * time.Second)
     }
-    return out, nil
+    return out
 }
 
+func basicAuth(user, pass string) string {
+    return base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+}
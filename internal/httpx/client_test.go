@@ -0,0 +1,70 @@
+package httpx
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+)
+
+func TestHostHeaderOverridesHost(t *testing.T) {
+    hosts := make(chan string, 10)
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        hosts <- r.Host
+    }))
+    defer srv.Close()
+    rawAddr, _ := rawServer(t, func(req *http.Request) string {
+        hosts <- req.Host
+        return "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"
+    })
+
+    for _, raw := range []string{RawNever, RawAlways} {
+        opt := &config.Options{RawHTTP: raw, Timeout: 5 * time.Second}
+        if err := opt.AddHeader("host: evil.example"); err != nil {
+            t.Fatal(err)
+        }
+        c, err := New(opt)
+        if err != nil {
+            t.Fatal(err)
+        }
+        base := srv.URL
+        if raw == RawAlways {
+            base = "http://" + rawAddr
+        }
+        tests := []struct {
+            name string
+            ro   *RequestOptions
+            want string
+        }{
+            {"-H Host", nil, "evil.example"},
+            {"request Host header", &RequestOptions{Headers: []Header{{Name: "Host", Value: "other.example"}}}, "other.example"},
+            {"header beats RequestOptions.Host", &RequestOptions{Host: "tpl.example"}, "evil.example"},
+        }
+        for _, tt := range tests {
+            resp, err := c.Do(base, "/", tt.ro)
+            if err != nil {
+                t.Fatalf("%s %s: %v", raw, tt.name, err)
+            }
+            select {
+            case got := <-hosts:
+                if got != tt.want {
+                    t.Errorf("%s %s: server saw Host %q, want %q", raw, tt.name, got, tt.want)
+                }
+            case <-time.After(5 * time.Second):
+                t.Fatalf("%s %s: the server did not accept the request", raw, tt.name)
+            }
+            n := 0
+            for _, h := range resp.Request.Headers {
+                if strings.EqualFold(h.Name, "Host") {
+                    n++
+                }
+            }
+            if n > 1 {
+                t.Errorf("%s %s: %d Host headers sent: %v", raw, tt.name, n, resp.Request.Headers)
+            }
+        }
+    }
+}
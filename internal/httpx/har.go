@@ -0,0 +1,169 @@
+package httpx
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "time"
+)
+
+// HAR 1.2 structures, limited to what the recorder fills in. Non-standard fields carry a
+// leading underscore as the format allows.
+type harEntry struct {
+    ID              uint64      `json:"_id"`
+    Error           string      `json:"_error,omitempty"`
+    StartedDateTime string      `json:"startedDateTime"`
+    Time            int64       `json:"time"`
+    Request         harRequest  `json:"request"`
+    Response        harResponse `json:"response"`
+    Cache           struct{}    `json:"cache"`
+    Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+    Method      string       `json:"method"`
+    URL         string       `json:"url"`
+    HTTPVersion string       `json:"httpVersion"`
+    Cookies     []struct{}   `json:"cookies"`
+    Headers     []Header     `json:"headers"`
+    QueryString []Header     `json:"queryString"`
+    PostData    *harPostData `json:"postData,omitempty"`
+    HeadersSize int          `json:"headersSize"`
+    BodySize    int          `json:"bodySize"`
+}
+
+type harPostData struct {
+    MimeType string `json:"mimeType"`
+    Text     string `json:"text"`
+}
+
+type harResponse struct {
+    Status      int        `json:"status"`
+    StatusText  string     `json:"statusText"`
+    HTTPVersion string     `json:"httpVersion"`
+    Cookies     []struct{} `json:"cookies"`
+    Headers     []Header   `json:"headers"`
+    Content     harContent `json:"content"`
+    RedirectURL string     `json:"redirectURL"`
+    HeadersSize int        `json:"headersSize"`
+    BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+    Size      int64  `json:"size"`
+    MimeType  string `json:"mimeType"`
+    Text      string `json:"text,omitempty"`
+    Encoding  string `json:"encoding,omitempty"`
+    Truncated bool   `json:"_truncated,omitempty"`
+}
+
+type harTimings struct {
+    Send    int64 `json:"send"`
+    Wait    int64 `json:"wait"`
+    Receive int64 `json:"receive"`
+}
+
+// harWriter streams entries into a HAR file. The closing brackets are written by close,
+// so an interrupted scan leaves a file that needs "]}}" appended to parse.
+type harWriter struct {
+    fp    *os.File
+    count int
+}
+
+func newHARWriter(name string) (*harWriter, error) {
+    fp, err := os.Create(name)
+    if err != nil {
+        return nil, err
+    }
+    head := `{"log":{"version":"1.2","creator":{"name":"pohek","version":"1.0.0"},"entries":[` + "\n"
+    if _, err := fp.WriteString(head); err != nil {
+        fp.Close()
+        return nil, err
+    }
+    return &harWriter{fp: fp}, nil
+}
+
+func (w *harWriter) write(ex *Exchange) error {
+    data, err := json.Marshal(harFromExchange(ex))
+    if err != nil {
+        return err
+    }
+    if w.count > 0 {
+        data = append([]byte(",\n"), data...)
+    }
+    w.count++
+    _, err = w.fp.Write(data)
+    return err
+}
+
+func (w *harWriter) close() error {
+    if _, err := w.fp.WriteString("\n]}}\n"); err != nil {
+        w.fp.Close()
+        return err
+    }
+    return w.fp.Close()
+}
+
+func harFromExchange(ex *Exchange) *harEntry {
+    e := &harEntry{
+        ID:              ex.ID,
+        Error:           ex.Error,
+        StartedDateTime: ex.Started.Format(time.RFC3339Nano),
+        Time:            ex.Elapsed,
+        Timings:         harTimings{Send: 0, Wait: ex.Elapsed, Receive: 0},
+        Request: harRequest{
+            Method:      ex.Method,
+            URL:         ex.URL,
+            HTTPVersion: "HTTP/1.1",
+            Cookies:     []struct{}{},
+            Headers:     []Header{},
+            QueryString: []Header{},
+            HeadersSize: -1,
+        },
+        Response: harResponse{
+            Cookies:     []struct{}{},
+            Headers:     []Header{},
+            HeadersSize: -1,
+        },
+    }
+    if q := strings.Index(ex.URL, "?"); q >= 0 {
+        // keep the raw values: the target may be deliberately malformed
+        for _, kv := range strings.Split(ex.URL[q+1:], "&") {
+            k, v, _ := strings.Cut(kv, "=")
+            e.Request.QueryString = append(e.Request.QueryString, Header{Name: k, Value: v})
+        }
+    }
+    if s := ex.Request; s != nil {
+        e.Request.HTTPVersion = s.Proto
+        e.Request.Headers = s.Headers
+        e.Request.BodySize = len(s.Body)
+        if s.Body != "" {
+            e.Request.PostData = &harPostData{MimeType: headerValue(s.Headers, "Content-Type"), Text: s.Body}
+        }
+    }
+    if r := ex.Response; r != nil {
+        e.Response.Status = r.Status
+        e.Response.StatusText = http.StatusText(r.Status)
+        e.Response.HTTPVersion = r.Proto
+        e.Response.Headers = r.Headers
+        e.Response.BodySize = r.BodySize
+        e.Response.Content = harContent{
+            Size:      r.BodySize,
+            MimeType:  headerValue(r.Headers, "Content-Type"),
+            Text:      r.Body,
+            Encoding:  r.BodyEncoding,
+            Truncated: r.BodyTruncated,
+        }
+        if loc := headerValue(r.Headers, "Location"); loc != "" {
+            if base, err := url.Parse(ex.URL); err == nil {
+                if u, err := base.Parse(loc); err == nil {
+                    loc = u.String()
+                }
+            }
+            e.Response.RedirectURL = loc
+        }
+    }
+    return e
+}
@@ -0,0 +1,108 @@
+package httpx
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// jarCookie is a single entry of a Netscape cookie file.
+type jarCookie struct {
+    domain     string
+    subdomains bool
+    path       string
+    secure     bool
+    expires    int64 // unix seconds; 0 = session cookie
+    name       string
+    value      string
+}
+
+// cookieJar is a read-only set of cookies imported from a Netscape-format cookie file
+// (as written by curl -c, wget and browser export extensions). Cookies are sent only to
+// hosts and paths they are scoped to.
+type cookieJar struct {
+    cookies []jarCookie
+}
+
+// loadCookieJar parses a Netscape cookie file. Lines starting with "#HttpOnly_" are
+// cookies; other "#" lines are comments.
+func loadCookieJar(name string) (*cookieJar, error) {
+    f, err := os.Open(name)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    j := &cookieJar{}
+    sc := bufio.NewScanner(f)
+    n := 0
+    for sc.Scan() {
+        n++
+        line := strings.TrimRight(sc.Text(), "\r")
+        line = strings.TrimPrefix(line, "#HttpOnly_")
+        if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        f := strings.Split(line, "\t")
+        if len(f) != 7 {
+            return nil, fmt.Errorf("%s:%d: expected 7 tab-separated fields, got %d", name, n, len(f))
+        }
+        exp, err := strconv.ParseInt(f[4], 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("%s:%d: bad expiry %q", name, n, f[4])
+        }
+        j.cookies = append(j.cookies, jarCookie{
+            domain:     strings.ToLower(strings.TrimPrefix(f[0], ".")),
+            subdomains: strings.EqualFold(f[1], "TRUE"),
+            path:       f[2],
+            secure:     strings.EqualFold(f[3], "TRUE"),
+            expires:    exp,
+            name:       f[5],
+            value:      f[6],
+        })
+    }
+    return j, sc.Err()
+}
+
+// header returns the Cookie header value for a request to host (without port) and path
+// (the request target; a query is ignored) over the given scheme, or "" if no cookie
+// applies. Expired cookies are skipped.
+func (j *cookieJar) header(scheme, host, path string) string {
+    if j == nil {
+        return ""
+    }
+    host = strings.ToLower(host)
+    now := time.Now().Unix()
+    var parts []string
+    for _, c := range j.cookies {
+        if c.expires != 0 && c.expires < now {
+            continue
+        }
+        if c.secure && scheme != "https" {
+            continue
+        }
+        if host != c.domain && !(c.subdomains && strings.HasSuffix(host, "."+c.domain)) {
+            continue
+        }
+        if c.path != "" && !pathMatch(path, c.path) {
+            continue
+        }
+        parts = append(parts, c.name+"="+c.value)
+    }
+    return strings.Join(parts, "; ")
+}
+
+// pathMatch implements the RFC 6265 path-match: a cookie for /api is sent to /api and
+// /api/x, but not to /apix or /api2.
+func pathMatch(reqPath, cookiePath string) bool {
+    if i := strings.IndexAny(reqPath, "?#"); i >= 0 {
+        reqPath = reqPath[:i]
+    }
+    if !strings.HasPrefix(reqPath, cookiePath) {
+        return false
+    }
+    return len(reqPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
@@ -0,0 +1,125 @@
+package httpx
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+)
+
+func TestPathMatch(t *testing.T) {
+    tests := []struct {
+        req, cookie string
+        want        bool
+    }{
+        {"/api", "/api", true},
+        {"/api/", "/api", true},
+        {"/api/x", "/api", true},
+        {"/api?x=1", "/api", true},
+        {"/apix", "/api", false},
+        {"/api2", "/api", false},
+        {"/ap", "/api", false},
+        {"/api/x", "/api/", true},
+        {"/api", "/api/", false},
+        {"/anything", "/", true},
+        {"/x?/api", "/api", false},
+    }
+    for _, tt := range tests {
+        if got := pathMatch(tt.req, tt.cookie); got != tt.want {
+            t.Errorf("pathMatch(%q, %q) = %v, want %v", tt.req, tt.cookie, got, tt.want)
+        }
+    }
+}
+
+func writeJar(t *testing.T, content string) string {
+    t.Helper()
+    name := filepath.Join(t.TempDir(), "cookies.txt")
+    if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return name
+}
+
+func TestCookieJar(t *testing.T) {
+    future := "9999999999"
+    lines := []string{
+        "# Netscape HTTP Cookie File",
+        "",
+        ".example.com\tTRUE\t/\tFALSE\t0\tall\t1",
+        "example.com\tFALSE\t/api\tFALSE\t" + future + "\tapi\t2",
+        "#HttpOnly_example.com\tFALSE\t/\tTRUE\t" + future + "\tsecure\t3",
+        "example.com\tFALSE\t/\tFALSE\t1\texpired\t4",
+        "other.example\tFALSE\t/\tFALSE\t0\tother\t5\r",
+    }
+    j, err := loadCookieJar(writeJar(t, strings.Join(lines, "\n")))
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        scheme, host, path string
+        want               string
+    }{
+        {"http", "example.com", "/", "all=1"},
+        {"https", "EXAMPLE.com", "/", "all=1; secure=3"},
+        {"http", "example.com", "/api/v1?q", "all=1; api=2"},
+        {"http", "example.com", "/apix", "all=1"},
+        {"http", "www.example.com", "/api", "all=1"}, // api is host-only
+        {"http", "badexample.com", "/", ""},
+        {"http", "other.example", "/", "other=5"},
+    }
+    for _, tt := range tests {
+        if got := j.header(tt.scheme, tt.host, tt.path); got != tt.want {
+            t.Errorf("header(%s, %s, %s) = %q, want %q", tt.scheme, tt.host, tt.path, got, tt.want)
+        }
+    }
+
+    for _, bad := range []string{"example.com\tTRUE\t/\tFALSE\t0\tname", "example.com\tTRUE\t/\tFALSE\tnever\tn\tv"} {
+        if _, err := loadCookieJar(writeJar(t, bad)); err == nil {
+            t.Errorf("loadCookieJar(%q): want error", bad)
+        }
+    }
+}
+
+func TestAuthOptions(t *testing.T) {
+    tests := []struct {
+        name    string
+        opt     config.Options
+        want    string
+        wantErr bool
+    }{
+        {"basic", config.Options{BasicAuth: "user:pa:ss"}, "Basic dXNlcjpwYTpzcw==", false},
+        {"bearer", config.Options{BearerToken: "tok"}, "Bearer tok", false},
+        {"none", config.Options{}, "", false},
+        {"basic without colon", config.Options{BasicAuth: "user"}, "", true},
+        {"both", config.Options{BasicAuth: "u:p", BearerToken: "t"}, "", true},
+    }
+    for _, tt := range tests {
+        tt.opt.Timeout = time.Second
+        c, err := New(&tt.opt)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("%s: want error", tt.name)
+            }
+            continue
+        }
+        if err != nil {
+            t.Fatalf("%s: %v", tt.name, err)
+        }
+        if got := headerValue(c.requestHeaders("http://h", "/", nil, nil), "Authorization"); got != tt.want {
+            t.Errorf("%s: Authorization = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+
+    // a per-request Authorization replaces the configured one
+    c, err := New(&config.Options{BearerToken: "tok", Timeout: time.Second})
+    if err != nil {
+        t.Fatal(err)
+    }
+    hs := c.requestHeaders("http://h", "/", []Header{{Name: "authorization", Value: "Bearer other"}}, nil)
+    if got := headerValue(hs, "Authorization"); got != "Bearer other" {
+        t.Errorf("per-request Authorization = %q", got)
+    }
+}
@@ -0,0 +1,107 @@
+package httpx
+
+import (
+    "context"
+    "crypto/tls"
+    "fmt"
+    "net"
+    "net/http"
+    "strings"
+
+    "golang.org/x/net/http2"
+)
+
+// Protocol selection for Options.Protocol and RequestOptions.Proto.
+const (
+    ProtoAuto   = "auto"    // HTTP/1.1, or h2 when net/http negotiates it over TLS
+    ProtoHTTP10 = "http1.0" // HTTP/1.0 over the raw socket transport
+    ProtoHTTP11 = "http1.1" // HTTP/1.1 only; h2 is never offered
+    ProtoH2     = "h2"      // HTTP/2 over TLS; fails if the server does not negotiate h2
+    ProtoH2C    = "h2c"     // cleartext HTTP/2 with prior knowledge
+)
+
+// CheckProto validates a protocol name; the empty string means the client default.
+func CheckProto(p string) error {
+    switch p {
+    case "", ProtoAuto, ProtoHTTP10, ProtoHTTP11, ProtoH2, ProtoH2C:
+        return nil
+    }
+    return fmt.Errorf("invalid protocol %q (want %s, %s, %s, %s or %s)", p, ProtoAuto, ProtoHTTP10, ProtoHTTP11, ProtoH2, ProtoH2C)
+}
+
+// http11Transport returns a copy of tr that never negotiates HTTP/2.
+func http11Transport(tr *http.Transport) *http.Transport {
+    t := tr.Clone()
+    t.ForceAttemptHTTP2 = false
+    t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+    if t.TLSClientConfig != nil {
+        t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+    }
+    return t
+}
+
+// h2Transport returns an HTTP/2-only transport. Connections are dialled by the raw
+// transport, so proxies (CONNECT) and TLS settings are the same as for HTTP/1.x.
+// With cleartext set it speaks h2c with prior knowledge to http:// origins.
+func h2Transport(raw *rawTransport, cleartext bool) http.RoundTripper {
+    t := &http2.Transport{DisableCompression: true, AllowHTTP: cleartext}
+    t.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+        if cleartext {
+            return raw.dialConn(ctx, "http", addr, nil)
+        }
+        conn, err := raw.dialConn(ctx, "https", addr, []string{http2.NextProtoTLS})
+        if err != nil {
+            return nil, err
+        }
+        if p := conn.(*tls.Conn).ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+            conn.Close()
+            return nil, fmt.Errorf("server did not negotiate h2 (ALPN %q)", p)
+        }
+        return conn, nil
+    }
+    return h2Paths{t}
+}
+
+// h2Paths keeps request targets with a leading "//" intact over HTTP/2. x/net/http2
+// derives :path from URL.RequestURI, which turns an opaque "//x" into "scheme://x" and
+// then only strips "scheme://host" from it, so the authority is put in front of the path.
+type h2Paths struct {
+    t *http2.Transport
+}
+
+func (h h2Paths) RoundTrip(req *http.Request) (*http.Response, error) {
+    if !strings.HasPrefix(req.URL.Opaque, "//") {
+        return h.t.RoundTrip(req)
+    }
+    host := req.Host
+    if host == "" {
+        host = req.URL.Host
+    }
+    r2 := req.Clone(req.Context())
+    r2.URL.Opaque = "//" + host + req.URL.Opaque
+    resp, err := h.t.RoundTrip(r2)
+    if resp != nil {
+        resp.Request = req
+    }
+    return resp, err
+}
+
+// roundTripper returns the net/http transport for proto and checks that the scheme of
+// baseURL can carry it.
+func (c *Client) roundTripper(proto, scheme string) (http.RoundTripper, error) {
+    switch proto {
+    case ProtoHTTP11:
+        return c.tr11, nil
+    case ProtoH2:
+        if scheme != "https" {
+            return nil, fmt.Errorf("h2 needs an https origin (use h2c for cleartext)")
+        }
+        return c.h2, nil
+    case ProtoH2C:
+        if scheme != "http" {
+            return nil, fmt.Errorf("h2c needs an http origin (use h2 over TLS)")
+        }
+        return c.h2c, nil
+    }
+    return c.tr, nil
+}
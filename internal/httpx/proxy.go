@@ -0,0 +1,193 @@
+package httpx
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "sync/atomic"
+
+    "golang.org/x/net/proxy"
+)
+
+// Proxy rotation strategies for Options.ProxyRotate.
+const (
+    RotateRoundRobin = "round-robin" // next proxy for every request (every connection for SOCKS)
+    RotateSticky     = "sticky"      // one proxy per target host, assigned round-robin on first use
+)
+
+// proxyPool holds the configured proxies. A list is either all HTTP(S) proxies, used
+// through the transports' Proxy hook, or all SOCKS5 proxies, which replace the dialer:
+// net/http only speaks SOCKS with remote resolution, so SOCKS is handled here to keep
+// socks5 (resolve locally) and socks5h (resolve on the proxy) apart.
+type proxyPool struct {
+    proxies []*url.URL
+    socks   bool
+    sticky  bool
+    next    uint32
+
+    mu     sync.Mutex
+    byHost map[string]*url.URL
+}
+
+// newProxyPool validates the proxy list. Entries without a scheme are HTTP proxies;
+// auth (user:password) is applied to entries that carry no credentials of their own.
+func newProxyPool(list []string, rotate, auth string) (*proxyPool, error) {
+    p := &proxyPool{byHost: make(map[string]*url.URL)}
+    switch rotate {
+    case "", RotateRoundRobin:
+    case RotateSticky:
+        p.sticky = true
+    default:
+        return nil, fmt.Errorf("invalid proxy rotation %q (want %s or %s)", rotate, RotateRoundRobin, RotateSticky)
+    }
+    var user *url.Userinfo
+    if auth != "" {
+        name, pass, ok := strings.Cut(auth, ":")
+        if !ok {
+            return nil, fmt.Errorf("proxy credentials must be user:password")
+        }
+        user = url.UserPassword(name, pass)
+    }
+    for _, raw := range list {
+        u, err := parseProxy(raw)
+        if err != nil {
+            return nil, err
+        }
+        if u.User == nil {
+            u.User = user
+        }
+        socks := strings.HasPrefix(u.Scheme, "socks")
+        if len(p.proxies) > 0 && socks != p.socks {
+            return nil, fmt.Errorf("proxy %s: HTTP and SOCKS proxies cannot be mixed in one list", u.Redacted())
+        }
+        p.socks = socks
+        p.proxies = append(p.proxies, u)
+    }
+    if len(p.proxies) == 0 {
+        return nil, fmt.Errorf("empty proxy list")
+    }
+    return p, nil
+}
+
+// parseProxy parses and checks a single proxy URL, filling in the default port.
+func parseProxy(raw string) (*url.URL, error) {
+    raw = strings.TrimSpace(raw)
+    if !strings.Contains(raw, "://") {
+        raw = "http://" + raw
+    }
+    u, err := url.Parse(raw)
+    if err != nil {
+        return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+    }
+    var port string
+    switch u.Scheme {
+    case "http":
+        port = "80"
+    case "https":
+        port = "443"
+    case "socks5", "socks5h":
+        port = "1080"
+    default:
+        return nil, fmt.Errorf("proxy %s: unsupported scheme %q (want http, https, socks5 or socks5h)", u.Redacted(), u.Scheme)
+    }
+    if u.Hostname() == "" {
+        return nil, fmt.Errorf("proxy %s: missing host", u.Redacted())
+    }
+    if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+        return nil, fmt.Errorf("proxy %s: unexpected path or query", u.Redacted())
+    }
+    u.Path = ""
+    if u.Port() == "" {
+        u.Host = net.JoinHostPort(u.Hostname(), port)
+    }
+    return u, nil
+}
+
+// pick returns the proxy for a request to host (host or host:port).
+func (p *proxyPool) pick(host string) *url.URL {
+    if len(p.proxies) == 1 {
+        return p.proxies[0]
+    }
+    if !p.sticky {
+        return p.proxies[(atomic.AddUint32(&p.next, 1)-1)%uint32(len(p.proxies))]
+    }
+    if h, _, err := net.SplitHostPort(host); err == nil {
+        host = h
+    }
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    u, ok := p.byHost[host]
+    if !ok {
+        u = p.proxies[p.next%uint32(len(p.proxies))]
+        p.next++
+        p.byHost[host] = u
+    }
+    return u
+}
+
+// httpProxy is the Proxy hook for HTTP(S) proxy lists.
+func (p *proxyPool) httpProxy(req *http.Request) (*url.URL, error) {
+    return p.pick(req.URL.Host), nil
+}
+
+// dial connects to addr through a SOCKS5 proxy. With socks5 the target host is resolved
+// locally and the proxy is given an IP; with socks5h the proxy resolves the name.
+func (p *proxyPool) dial(ctx context.Context, forward *net.Dialer, network, addr string) (net.Conn, error) {
+    u := p.pick(addr)
+    if u.Scheme == "socks5" {
+        host, port, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, err
+        }
+        if net.ParseIP(host) == nil {
+            ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+            if err != nil {
+                return nil, err
+            }
+            if len(ips) == 0 {
+                return nil, fmt.Errorf("lookup %s: no addresses", host)
+            }
+            addr = net.JoinHostPort(ips[0].IP.String(), port)
+        }
+    }
+    var auth *proxy.Auth
+    if u.User != nil {
+        pass, _ := u.User.Password()
+        auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+    }
+    d, err := proxy.SOCKS5("tcp", u.Host, auth, forward)
+    if err != nil {
+        return nil, err
+    }
+    conn, err := d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+    if err != nil {
+        return nil, fmt.Errorf("socks proxy %s: %w", u.Redacted(), err)
+    }
+    return conn, nil
+}
+
+// absoluteForm wraps an HTTP/1.x transport so that plain-HTTP requests going to a forward
+// proxy carry an absolute-form target with the raw path intact. net/http writes an opaque
+// URL verbatim even to a proxy, which then sees an origin-form request it cannot route;
+// an opaque "//host/path" is written as "http://host/path" instead.
+type absoluteForm struct {
+    t       http.RoundTripper
+    proxied func(*http.Request) bool
+}
+
+func (a absoluteForm) RoundTrip(req *http.Request) (*http.Response, error) {
+    if req.URL.Scheme != "http" || req.URL.Opaque == "" || strings.HasPrefix(req.URL.Opaque, "//") || !a.proxied(req) {
+        return a.t.RoundTrip(req)
+    }
+    r2 := req.Clone(req.Context())
+    r2.URL.Opaque = "//" + req.URL.Host + req.URL.Opaque
+    resp, err := a.t.RoundTrip(r2)
+    if resp != nil {
+        resp.Request = req
+    }
+    return resp, err
+}
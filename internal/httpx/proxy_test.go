@@ -0,0 +1,128 @@
+package httpx
+
+import (
+    "net/http"
+    "net/url"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseProxy(t *testing.T) {
+    tests := []struct {
+        raw     string
+        want    string
+        wantErr string
+    }{
+        {raw: "proxy.local", want: "http://proxy.local:80"},
+        {raw: " proxy.local:3128 ", want: "http://proxy.local:3128"},
+        {raw: "https://proxy.local", want: "https://proxy.local:443"},
+        {raw: "socks5://127.0.0.1", want: "socks5://127.0.0.1:1080"},
+        {raw: "socks5h://user:p%40ss@[::1]:9050", want: "socks5h://user:p%40ss@[::1]:9050"},
+        {raw: "http://proxy.local:8080/", want: "http://proxy.local:8080"},
+        {raw: "ftp://proxy.local", wantErr: "unsupported scheme"},
+        {raw: "socks4://proxy.local", wantErr: "unsupported scheme"},
+        {raw: "http://", wantErr: "missing host"},
+        {raw: "http://proxy.local/path", wantErr: "unexpected path"},
+        {raw: "http://proxy.local/?a=b", wantErr: "unexpected path"},
+    }
+    for _, tt := range tests {
+        u, err := parseProxy(tt.raw)
+        if tt.wantErr != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("parseProxy(%q) error = %v, want %q", tt.raw, err, tt.wantErr)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("parseProxy(%q): %v", tt.raw, err)
+            continue
+        }
+        if u.String() != tt.want {
+            t.Errorf("parseProxy(%q) = %s, want %s", tt.raw, u, tt.want)
+        }
+    }
+}
+
+func TestParseResolve(t *testing.T) {
+    tests := []struct {
+        entries []string
+        want    map[string]string
+        wantErr bool
+    }{
+        {entries: []string{"Example.com:443:10.0.0.1"}, want: map[string]string{"example.com:443": "10.0.0.1:443"}},
+        {entries: []string{"a:80:::1", "b:8443:[2001:db8::2]"}, want: map[string]string{"a:80": "[::1]:80", "b:8443": "[2001:db8::2]:8443"}},
+        {entries: []string{"host:80:backend.internal"}, want: map[string]string{"host:80": "backend.internal:80"}},
+        {entries: []string{"host:80"}, wantErr: true},
+        {entries: []string{":80:1.2.3.4"}, wantErr: true},
+        {entries: []string{"host::1.2.3.4"}, wantErr: true},
+        {entries: []string{"host:80:"}, wantErr: true},
+        {entries: []string{"host:80:not/an/addr"}, wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := parseResolve(tt.entries)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("parseResolve(%q) = %v, want error", tt.entries, got)
+            }
+            continue
+        }
+        if err != nil || !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("parseResolve(%q) = %v, %v; want %v", tt.entries, got, err, tt.want)
+        }
+    }
+}
+
+func TestCheckResolveProxy(t *testing.T) {
+    overrides := map[string]string{"target:443": "10.0.0.1:443"}
+    proxied := func(*http.Request) (*url.URL, error) { return url.Parse("http://proxy:3128") }
+    direct := func(*http.Request) (*url.URL, error) { return nil, nil }
+    onlyOther := func(r *http.Request) (*url.URL, error) {
+        if r.URL.Hostname() == "other" {
+            return url.Parse("http://proxy:3128")
+        }
+        return nil, nil
+    }
+    if err := checkResolveProxy(overrides, proxied); err == nil {
+        t.Error("override behind an HTTP proxy accepted")
+    }
+    if err := checkResolveProxy(overrides, direct); err != nil {
+        t.Errorf("direct connection rejected: %v", err)
+    }
+    if err := checkResolveProxy(overrides, onlyOther); err != nil {
+        t.Errorf("override for a host the proxy does not handle rejected: %v", err)
+    }
+}
+
+func TestProxyPoolRotation(t *testing.T) {
+    p, err := newProxyPool([]string{"p1:1", "p2:2", "p3:3"}, RotateRoundRobin, "u:pw")
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for i := 0; i < 4; i++ {
+        got = append(got, p.pick("h").Host)
+    }
+    if want := []string{"p1:1", "p2:2", "p3:3", "p1:1"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("round-robin = %v, want %v", got, want)
+    }
+    if pw, _ := p.proxies[0].User.Password(); pw != "pw" {
+        t.Errorf("proxy auth not applied: %v", p.proxies[0].User)
+    }
+
+    s, err := newProxyPool([]string{"p1:1", "p2:2"}, RotateSticky, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    a, b := s.pick("a:443"), s.pick("b")
+    if a == b || s.pick("a:80") != a || s.pick("b:443") != b {
+        t.Errorf("sticky rotation does not pin hosts: a=%s b=%s", a, b)
+    }
+
+    if _, err := newProxyPool([]string{"http://p1", "socks5://p2"}, "", ""); err == nil {
+        t.Error("mixed HTTP and SOCKS list accepted")
+    }
+    if _, err := newProxyPool([]string{"p1"}, "random", ""); err == nil {
+        t.Error("unknown rotation accepted")
+    }
+}
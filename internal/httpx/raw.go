@@ -0,0 +1,332 @@
+package httpx
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "crypto/tls"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Transport selection modes for Options.RawHTTP.
+const (
+    RawAuto   = "auto"   // raw socket only for targets net/http would reject or rewrite
+    RawAlways = "always" // every request goes over the raw socket transport
+    RawNever  = "never"  // always use net/http
+)
+
+// needsRaw reports whether net/http would reject or rewrite the request target:
+// control characters are refused, a leading "//" is turned into scheme://...,
+// and spaces, '#' and non-ASCII bytes are not guaranteed to survive across
+// protocol versions.
+func needsRaw(target string) bool {
+    if strings.HasPrefix(target, "//") {
+        return true
+    }
+    for i := 0; i < len(target); i++ {
+        b := target[i]
+        if b <= ' ' || b == '#' || b >= 0x7f {
+            return true
+        }
+    }
+    return false
+}
+
+// rawRequest is a request in wire form. Target and header values are written verbatim.
+type rawRequest struct {
+    Scheme  string
+    Addr    string // host:port to connect to
+    Method  string
+    Target  string
+    Proto   string
+    Host    string
+    Headers []Header // without Host and Content-Length
+    Body    []byte
+}
+
+// sent returns the SentRequest describing exactly what write puts on the wire.
+func (r *rawRequest) sent() *SentRequest {
+    headers := make([]Header, 0, len(r.Headers)+2)
+    headers = append(headers, Header{Name: "Host", Value: r.Host})
+    headers = append(headers, r.Headers...)
+    if len(r.Body) > 0 {
+        headers = append(headers, Header{Name: "Content-Length", Value: fmt.Sprint(len(r.Body))})
+    }
+    return &SentRequest{
+        Method:  r.Method,
+        Target:  r.Target,
+        Proto:   r.Proto,
+        Host:    r.Host,
+        Headers: headers,
+        Body:    string(r.Body),
+    }
+}
+
+func (r *rawRequest) write(w io.Writer) error {
+    bw := bufio.NewWriter(w)
+    s := r.sent()
+    bw.WriteString(s.RequestLine())
+    bw.WriteString("\r\n")
+    for _, h := range s.Headers {
+        bw.WriteString(h.Name)
+        bw.WriteString(": ")
+        bw.WriteString(h.Value)
+        bw.WriteString("\r\n")
+    }
+    bw.WriteString("\r\n")
+    bw.Write(r.Body)
+    return bw.Flush()
+}
+
+// rawConn is a pooled connection with its buffered reader.
+type rawConn struct {
+    net.Conn
+    br *bufio.Reader
+}
+
+// rawTransport writes HTTP/1.x requests byte-for-byte over TCP or TLS and keeps idle
+// connections per origin for reuse. It never follows redirects.
+type rawTransport struct {
+    dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+    tls     *tls.Config
+    proxy   func(*http.Request) (*url.URL, error)
+    timeout time.Duration
+    maxIdle int
+
+    mu   sync.Mutex
+    idle map[string][]*rawConn
+}
+
+// newRawTransport returns a raw transport dialling with dial (the same dialer as the
+// net/http transport, so SOCKS proxies apply to both).
+func newRawTransport(tlsConf *tls.Config, proxy func(*http.Request) (*url.URL, error), dial func(ctx context.Context, network, addr string) (net.Conn, error), timeout time.Duration) *rawTransport {
+    return &rawTransport{
+        dial:    dial,
+        tls:     tlsConf,
+        proxy:   proxy,
+        timeout: timeout,
+        maxIdle: 100,
+        idle:    make(map[string][]*rawConn),
+    }
+}
+
+// roundTrip sends r and returns the parsed response together with its headers in
+// received order and casing. The response body must be closed; the connection goes back
+// to the pool only if the body was read to EOF.
+// A request on a reused connection that fails before any response is retried once on a
+// fresh connection, since the server may have closed the idle connection.
+func (t *rawTransport) roundTrip(ctx context.Context, r *rawRequest) (*http.Response, []Header, error) {
+    key := r.Scheme + "://" + r.Addr
+    for attempt := 0; ; attempt++ {
+        rc, reused := t.get(key)
+        if rc == nil {
+            var err error
+            if rc, err = t.open(ctx, r); err != nil {
+                return nil, nil, err
+            }
+        }
+        if dl, ok := ctx.Deadline(); ok {
+            rc.SetDeadline(dl)
+        } else if t.timeout > 0 {
+            rc.SetDeadline(time.Now().Add(t.timeout))
+        }
+        resp, headers, err := t.exchange(rc, r)
+        if err != nil {
+            rc.Close()
+            if reused && attempt == 0 {
+                continue
+            }
+            return nil, nil, err
+        }
+        reusable := !resp.Close && r.Proto == "HTTP/1.1"
+        resp.Body = &rawBody{rc: resp.Body, done: func(eof bool) {
+            if eof && reusable {
+                rc.SetDeadline(time.Time{})
+                t.put(key, rc)
+                return
+            }
+            rc.Close()
+        }}
+        return resp, headers, nil
+    }
+}
+
+// exchange writes r and reads the response head itself, so that header order and casing
+// can be kept, before handing it to http.ReadResponse for body framing.
+func (t *rawTransport) exchange(rc *rawConn, r *rawRequest) (*http.Response, []Header, error) {
+    if err := r.write(rc); err != nil {
+        return nil, nil, err
+    }
+    var head bytes.Buffer
+    var headers []Header
+    for first := true; ; first = false {
+        line, err := rc.br.ReadSlice('\n')
+        if err != nil {
+            return nil, nil, err
+        }
+        head.Write(line)
+        l := strings.TrimRight(string(line), "\r\n")
+        if l == "" {
+            break
+        }
+        if first {
+            continue
+        }
+        if i := strings.Index(l, ":"); i > 0 {
+            headers = append(headers, Header{Name: l[:i], Value: strings.TrimSpace(l[i+1:])})
+        }
+        if head.Len() > 1<<20 {
+            return nil, nil, fmt.Errorf("response header too large")
+        }
+    }
+    br := bufio.NewReader(io.MultiReader(&head, rc.br))
+    resp, err := http.ReadResponse(br, &http.Request{Method: r.Method})
+    if err != nil {
+        return nil, nil, err
+    }
+    if tc, ok := rc.Conn.(*tls.Conn); ok {
+        cs := tc.ConnectionState()
+        resp.TLS = &cs
+    }
+    return resp, headers, nil
+}
+
+func (t *rawTransport) get(key string) (*rawConn, bool) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    conns := t.idle[key]
+    if len(conns) == 0 {
+        return nil, false
+    }
+    rc := conns[len(conns)-1]
+    t.idle[key] = conns[:len(conns)-1]
+    return rc, true
+}
+
+func (t *rawTransport) put(key string, rc *rawConn) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if len(t.idle[key]) >= t.maxIdle {
+        rc.Close()
+        return
+    }
+    t.idle[key] = append(t.idle[key], rc)
+}
+
+// open opens an HTTP/1.1 connection for r.
+func (t *rawTransport) open(ctx context.Context, r *rawRequest) (*rawConn, error) {
+    conn, err := t.dialConn(ctx, r.Scheme, r.Addr, []string{"http/1.1"})
+    if err != nil {
+        return nil, err
+    }
+    return &rawConn{Conn: conn, br: bufio.NewReader(conn)}, nil
+}
+
+// dialConn connects to addr, tunnelling through an HTTP(S) proxy with CONNECT when the
+// client is configured with one, and wraps the connection in TLS offering alpn for https.
+// The HTTP/2 transports dial through here too.
+func (t *rawTransport) dialConn(ctx context.Context, scheme, addr string, alpn []string) (net.Conn, error) {
+    var proxyURL *url.URL
+    if t.proxy != nil {
+        pu, err := t.proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+        if err != nil {
+            return nil, err
+        }
+        proxyURL = pu
+    }
+
+    dialAddr := addr
+    if proxyURL != nil {
+        dialAddr = proxyURL.Host
+    }
+    conn, err := t.dial(ctx, "tcp", dialAddr)
+    if err != nil {
+        return nil, err
+    }
+    if proxyURL != nil && proxyURL.Scheme == "https" {
+        tc := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), InsecureSkipVerify: t.tls.InsecureSkipVerify})
+        if err := tc.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, fmt.Errorf("proxy %s: %w", proxyURL.Redacted(), err)
+        }
+        conn = tc
+    }
+    if proxyURL != nil {
+        if err := connectTunnel(conn, addr, proxyURL); err != nil {
+            conn.Close()
+            return nil, err
+        }
+    }
+    if scheme == "https" {
+        cfg := t.tls.Clone()
+        if cfg.ServerName == "" {
+            host, _, _ := net.SplitHostPort(addr)
+            cfg.ServerName = host
+        }
+        cfg.NextProtos = alpn
+        tc := tls.Client(conn, cfg)
+        if err := tc.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, err
+        }
+        conn = tc
+    }
+    return conn, nil
+}
+
+// connectTunnel asks an HTTP proxy to open a tunnel to addr. Tunnelling is used for both
+// schemes so that the request target is never rewritten into absolute-form by the proxy.
+func connectTunnel(conn net.Conn, addr string, proxyURL *url.URL) error {
+    req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+    if u := proxyURL.User; u != nil {
+        pass, _ := u.Password()
+        req += "Proxy-Authorization: Basic " + basicAuth(u.Username(), pass) + "\r\n"
+    }
+    req += "\r\n"
+    if _, err := io.WriteString(conn, req); err != nil {
+        return err
+    }
+    br := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+    }
+    if br.Buffered() > 0 {
+        return fmt.Errorf("proxy CONNECT %s: unexpected data after response", addr)
+    }
+    return nil
+}
+
+// rawBody reports whether the body was consumed to EOF when it is closed, which decides
+// if the underlying connection can be reused.
+type rawBody struct {
+    rc   io.ReadCloser
+    eof  bool
+    done func(eof bool)
+}
+
+func (b *rawBody) Read(p []byte) (int, error) {
+    n, err := b.rc.Read(p)
+    if err == io.EOF {
+        b.eof = true
+    }
+    return n, err
+}
+
+func (b *rawBody) Close() error {
+    // hand the connection back (or close it) before closing the body, so an unread
+    // body on a dead or endless stream does not block on draining
+    b.done(b.eof)
+    return b.rc.Close()
+}
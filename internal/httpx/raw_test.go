@@ -0,0 +1,196 @@
+package httpx
+
+import (
+    "bufio"
+    "context"
+    "crypto/tls"
+    "io"
+    "net"
+    "net/http"
+    "reflect"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestNeedsRaw(t *testing.T) {
+    tests := []struct {
+        target string
+        want   bool
+    }{
+        {"/", false},
+        {"/a/b?c=d", false},
+        {"/..;/admin", false},
+        {"/%2e%2e/x", false},
+        {"//evil/x", true},
+        {"/a b", true},
+        {"/a\tb", true},
+        {"/a#frag", true},
+        {"/caf\xc3\xa9", true},
+        {"/\x7f", true},
+        {"/x\r\nInjected: 1", true},
+    }
+    for _, tt := range tests {
+        if got := needsRaw(tt.target); got != tt.want {
+            t.Errorf("needsRaw(%q) = %v, want %v", tt.target, got, tt.want)
+        }
+    }
+}
+
+func TestRawRequestWrite(t *testing.T) {
+    r := &rawRequest{
+        Method:  "POST",
+        Target:  "/a b/../x",
+        Proto:   "HTTP/1.1",
+        Host:    "example.com",
+        Headers: []Header{{Name: "x-lower", Value: "1"}, {Name: "X-Upper", Value: "2"}},
+        Body:    []byte("abc"),
+    }
+    var b strings.Builder
+    if err := r.write(&b); err != nil {
+        t.Fatal(err)
+    }
+    want := "POST /a b/../x HTTP/1.1\r\nHost: example.com\r\nx-lower: 1\r\nX-Upper: 2\r\nContent-Length: 3\r\n\r\nabc"
+    if b.String() != want {
+        t.Errorf("wire =\n%q\nwant\n%q", b.String(), want)
+    }
+}
+
+// rawServer answers every request on a connection with the next response of respond,
+// counting accepted connections.
+func rawServer(t *testing.T, respond func(req *http.Request) string) (string, *int32) {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+    var conns int32
+    go func() {
+        for {
+            c, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            atomic.AddInt32(&conns, 1)
+            go func(c net.Conn) {
+                defer c.Close()
+                br := bufio.NewReader(c)
+                for {
+                    req, err := http.ReadRequest(br)
+                    if err != nil {
+                        return
+                    }
+                    io.Copy(io.Discard, req.Body)
+                    if _, err := io.WriteString(c, respond(req)); err != nil {
+                        return
+                    }
+                }
+            }(c)
+        }
+    }()
+    return ln.Addr().String(), &conns
+}
+
+func newTestRawTransport() *rawTransport {
+    d := &net.Dialer{}
+    return newRawTransport(&tls.Config{}, nil, d.DialContext, 5*time.Second)
+}
+
+func rawGet(t *testing.T, tr *rawTransport, addr, target, proto string) (*http.Response, []Header, string) {
+    t.Helper()
+    resp, headers, err := tr.roundTrip(context.Background(), &rawRequest{Scheme: "http", Addr: addr, Method: "GET", Target: target, Proto: proto, Host: addr})
+    if err != nil {
+        t.Fatalf("roundTrip %s: %v", target, err)
+    }
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    return resp, headers, string(body)
+}
+
+func TestRawTransportKeepAliveAndHeaders(t *testing.T) {
+    addr, conns := rawServer(t, func(req *http.Request) string {
+        switch req.URL.Path {
+        case "/chunked":
+            return "HTTP/1.1 200 OK\r\ntransfer-encoding: chunked\r\n\r\n3\r\nabc\r\n2\r\nde\r\n0\r\n\r\n"
+        case "/close":
+            return "HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Length: 2\r\n\r\nok"
+        }
+        return "HTTP/1.1 404 Not Found\r\nx-b: 2\r\nX-A: 1\r\nx-b: 3\r\nContent-Length: 4\r\n\r\nnope"
+    })
+    tr := newTestRawTransport()
+
+    resp, headers, body := rawGet(t, tr, addr, "/missing", "HTTP/1.1")
+    if resp.StatusCode != 404 || body != "nope" {
+        t.Errorf("got %d %q", resp.StatusCode, body)
+    }
+    want := []Header{{Name: "x-b", Value: "2"}, {Name: "X-A", Value: "1"}, {Name: "x-b", Value: "3"}, {Name: "Content-Length", Value: "4"}}
+    if !reflect.DeepEqual(headers, want) {
+        t.Errorf("headers = %v, want %v (received order and casing)", headers, want)
+    }
+    if _, _, body = rawGet(t, tr, addr, "/chunked", "HTTP/1.1"); body != "abcde" {
+        t.Errorf("chunked body = %q", body)
+    }
+    if n := atomic.LoadInt32(conns); n != 1 {
+        t.Errorf("%d connections after two keep-alive requests, want 1", n)
+    }
+
+    // Connection: close and HTTP/1.0 responses are not pooled
+    rawGet(t, tr, addr, "/close", "HTTP/1.1")
+    rawGet(t, tr, addr, "/x", "HTTP/1.1")
+    if n := atomic.LoadInt32(conns); n != 2 {
+        t.Errorf("%d connections after Connection: close, want 2", n)
+    }
+    rawGet(t, tr, addr, "/x", "HTTP/1.0")
+    rawGet(t, tr, addr, "/x", "HTTP/1.1")
+    if n := atomic.LoadInt32(conns); n != 3 {
+        t.Errorf("%d connections after an HTTP/1.0 request, want 3", n)
+    }
+}
+
+func TestRawTransportUnreadBodyIsNotPooled(t *testing.T) {
+    addr, conns := rawServer(t, func(*http.Request) string {
+        return "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello"
+    })
+    tr := newTestRawTransport()
+    resp, _, err := tr.roundTrip(context.Background(), &rawRequest{Scheme: "http", Addr: addr, Method: "GET", Target: "/", Proto: "HTTP/1.1", Host: addr})
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close() // body not read
+    rawGet(t, tr, addr, "/", "HTTP/1.1")
+    if n := atomic.LoadInt32(conns); n != 2 {
+        t.Errorf("%d connections, want 2 (a connection with an unread body must not be reused)", n)
+    }
+}
+
+func TestRawTransportRetriesStaleConnection(t *testing.T) {
+    // the server closes every connection after one response without announcing it
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+    go func() {
+        for {
+            c, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            go func(c net.Conn) {
+                defer c.Close()
+                if _, err := http.ReadRequest(bufio.NewReader(c)); err == nil {
+                    io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+                }
+            }(c)
+        }
+    }()
+    tr := newTestRawTransport()
+    addr := ln.Addr().String()
+    rawGet(t, tr, addr, "/", "HTTP/1.1")
+    time.Sleep(50 * time.Millisecond)
+    if _, _, body := rawGet(t, tr, addr, "/", "HTTP/1.1"); body != "ok" {
+        t.Errorf("body after a stale pooled connection = %q", body)
+    }
+}
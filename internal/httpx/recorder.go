@@ -0,0 +1,317 @@
+package httpx
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+)
+
+// Traffic recording modes for Options.TrafficMode.
+const (
+    TrafficAll      = "all"      // every exchange is written as soon as it completes
+    TrafficFindings = "findings" // exchanges are buffered and only written when Keep is called
+)
+
+// Defaults for the traffic recorder.
+const (
+    DefaultTrafficBody   = 64 << 10  // body bytes kept per request and per response
+    DefaultTrafficRotate = 100 << 20 // JSONL file size before rotation
+    trafficBuffer        = 1000      // exchanges held back in findings mode
+)
+
+// Exchange is one recorded request/response pair. Bodies are cut to the recorder's
+// limit and secrets in headers are redacted unless redaction is disabled. Error is set
+// when no response was received.
+type Exchange struct {
+    ID       uint64            `json:"id"`
+    Started  time.Time         `json:"started"`
+    Elapsed  int64             `json:"elapsed_ms"`
+    URL      string            `json:"url"`
+    Method   string            `json:"method"`
+    Request  *SentRequest      `json:"request,omitempty"`
+    Response *ExchangeResponse `json:"response,omitempty"`
+    Error    string            `json:"error,omitempty"`
+}
+
+// ExchangeResponse is the recorded part of a Response. Non-UTF-8 bodies are base64
+// encoded (BodyEncoding "base64").
+type ExchangeResponse struct {
+    Status        int      `json:"status"`
+    Proto         string   `json:"proto"`
+    Headers       []Header `json:"headers"`
+    Body          string   `json:"body,omitempty"`
+    BodyEncoding  string   `json:"body_encoding,omitempty"`
+    BodySize      int64    `json:"body_size"`
+    BodyTruncated bool     `json:"body_truncated,omitempty"` // cut by the recorder or the client
+}
+
+// trafficWriter is an output format for recorded exchanges.
+type trafficWriter interface {
+    write(*Exchange) error
+    close() error
+}
+
+// Recorder writes the exchanges of a Client to a HAR file or a rotating JSONL log.
+type Recorder struct {
+    w        trafficWriter
+    findings bool
+    maxBody  int
+    redact   *Redactor // nil disables redaction
+
+    mu      sync.Mutex
+    seq     uint64
+    pending map[uint64]*Exchange // findings mode: recent exchanges not written yet
+    order   []uint64
+    written map[uint64]bool // findings mode: pending exchanges already written
+    pins    map[uint64]int // findings mode: exchanges in use by a target, never evicted
+}
+
+// NewRecorder creates a recorder writing to name: a HAR file when the name ends in
+// ".har", a JSONL log rotated at rotate bytes otherwise. maxBody limits recorded bodies
+// (0 = DefaultTrafficBody). Cookie, Set-Cookie, Authorization and Proxy-Authorization
+// are redacted along with extraRedact, unless redact is false.
+func NewRecorder(name, mode string, maxBody, rotate int64, redact bool, extraRedact []string) (*Recorder, error) {
+    r := &Recorder{pending: make(map[uint64]*Exchange), written: make(map[uint64]bool), pins: make(map[uint64]int), maxBody: int(maxBody)}
+    switch mode {
+    case "", TrafficAll:
+    case TrafficFindings:
+        r.findings = true
+    default:
+        return nil, fmt.Errorf("invalid traffic mode %q (want %s or %s)", mode, TrafficAll, TrafficFindings)
+    }
+    if r.maxBody <= 0 {
+        r.maxBody = DefaultTrafficBody
+    }
+    if redact {
+        r.redact = NewRedactor(extraRedact)
+    }
+    if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+        return nil, err
+    }
+    var err error
+    if strings.EqualFold(filepath.Ext(name), ".har") {
+        r.w, err = newHARWriter(name)
+    } else {
+        if rotate <= 0 {
+            rotate = DefaultTrafficRotate
+        }
+        r.w, err = newJSONLWriter(name, rotate)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+// next returns a new exchange ID.
+func (r *Recorder) next() uint64 {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.seq++
+    return r.seq
+}
+
+// record stores exchange id; resp is nil when the request failed with err.
+func (r *Recorder) record(id uint64, start time.Time, url, method string, resp *Response, err error) {
+    ex := &Exchange{ID: id, Started: start, Elapsed: time.Since(start).Milliseconds(), URL: url, Method: method}
+    if err != nil {
+        ex.Error = err.Error()
+    }
+    if resp != nil {
+        ex.URL = resp.RequestURL
+        if resp.Request != nil {
+            sent := *resp.Request
+            sent.Headers = r.redact.Headers(sent.Headers)
+            if len(sent.Body) > r.maxBody {
+                sent.Body = sent.Body[:r.maxBody]
+            }
+            ex.Request = &sent
+            ex.Method = sent.Method
+        }
+        rr := &ExchangeResponse{
+            Status:        resp.StatusCode,
+            Proto:         resp.Proto,
+            Headers:       r.redact.Headers(resp.Headers),
+            BodySize:      resp.BodySize,
+            BodyTruncated: resp.BodyTruncated,
+        }
+        body := resp.Body
+        if len(body) > r.maxBody {
+            body, rr.BodyTruncated = body[:r.maxBody], true
+        }
+        if utf8.Valid(body) {
+            rr.Body = string(body)
+        } else {
+            rr.Body, rr.BodyEncoding = base64.StdEncoding.EncodeToString(body), "base64"
+        }
+        ex.Response = rr
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if !r.findings {
+        r.writeLocked(ex)
+        return
+    }
+    r.pending[id] = ex
+    r.order = append(r.order, id)
+    r.trimLocked()
+}
+
+// trimLocked drops the oldest unpinned exchanges beyond trafficBuffer.
+func (r *Recorder) trimLocked() {
+    excess := len(r.order) - trafficBuffer
+    if excess <= 0 {
+        return
+    }
+    keep := r.order[:0]
+    for _, id := range r.order {
+        if excess > 0 && r.pins[id] == 0 {
+            // once out of pending an exchange cannot be kept again, so written may forget it
+            delete(r.pending, id)
+            delete(r.written, id)
+            excess--
+            continue
+        }
+        keep = append(keep, id)
+    }
+    r.order = keep
+}
+
+// Pin keeps exchanges in the findings-mode buffer until they are unpinned, however much
+// traffic other workers record meanwhile; a target pins its baselines while its payloads
+// run, so a finding can still reference them. Pins nest. ID 0 (not recorded) is ignored.
+func (r *Recorder) Pin(ids ...uint64) {
+    if r == nil || !r.findings {
+        return
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for _, id := range ids {
+        if id != 0 {
+            r.pins[id]++
+        }
+    }
+}
+
+// Unpin releases exchanges pinned with Pin.
+func (r *Recorder) Unpin(ids ...uint64) {
+    if r == nil || !r.findings {
+        return
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for _, id := range ids {
+        if r.pins[id] > 1 {
+            r.pins[id]--
+        } else {
+            delete(r.pins, id)
+        }
+    }
+    r.trimLocked()
+}
+
+// Keep writes the given exchanges in findings mode (each at most once). Exchanges that
+// already left the buffer (unpinned and older than the last trafficBuffer) are skipped. In
+// "all" mode everything is written already.
+func (r *Recorder) Keep(ids ...uint64) {
+    if r == nil || !r.findings {
+        return
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for _, id := range ids {
+        if ex, ok := r.pending[id]; ok && !r.written[id] {
+            r.writeLocked(ex)
+        }
+    }
+}
+
+func (r *Recorder) writeLocked(ex *Exchange) {
+    if r.findings {
+        r.written[ex.ID] = true
+    }
+    if err := r.w.write(ex); err != nil {
+        fmt.Printf("[traffic] write failed: %v\n", err)
+    }
+}
+
+// Close finishes the output (a HAR file is only complete after Close).
+func (r *Recorder) Close() error {
+    if r == nil {
+        return nil
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.w.close()
+}
+
+// jsonlWriter appends one exchange per line and rotates the file once it exceeds the
+// size limit: the full file is renamed to name.1, name.2, ... and a new one is started.
+type jsonlWriter struct {
+    name    string
+    limit   int64
+    fp      *os.File
+    size    int64
+    rotated int
+}
+
+func newJSONLWriter(name string, limit int64) (*jsonlWriter, error) {
+    w := &jsonlWriter{name: name, limit: limit}
+    return w, w.open()
+}
+
+func (w *jsonlWriter) open() error {
+    fp, err := os.OpenFile(w.name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil {
+        return err
+    }
+    st, err := fp.Stat()
+    if err != nil {
+        fp.Close()
+        return err
+    }
+    w.fp, w.size = fp, st.Size()
+    return nil
+}
+
+func (w *jsonlWriter) write(ex *Exchange) error {
+    line, err := json.Marshal(ex)
+    if err != nil {
+        return err
+    }
+    line = append(line, '\n')
+    if w.size > 0 && w.size+int64(len(line)) > w.limit {
+        if err := w.rotate(); err != nil {
+            return err
+        }
+    }
+    n, err := w.fp.Write(line)
+    w.size += int64(n)
+    return err
+}
+
+func (w *jsonlWriter) rotate() error {
+    if err := w.fp.Close(); err != nil {
+        return err
+    }
+    for {
+        w.rotated++
+        dst := fmt.Sprintf("%s.%d", w.name, w.rotated)
+        if _, err := os.Stat(dst); os.IsNotExist(err) {
+            if err := os.Rename(w.name, dst); err != nil {
+                return err
+            }
+            break
+        }
+    }
+    return w.open()
+}
+
+func (w *jsonlWriter) close() error { return w.fp.Close() }
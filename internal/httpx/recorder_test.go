@@ -0,0 +1,75 @@
+package httpx
+
+import (
+    "bufio"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+)
+
+func TestRecorderFindingsKeepsPinnedExchanges(t *testing.T) {
+    name := filepath.Join(t.TempDir(), "traffic.jsonl")
+    r, err := NewRecorder(name, TrafficFindings, 0, 0, true, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    add := func() uint64 {
+        id := r.next()
+        r.record(id, time.Now(), "http://h/", "GET", &Response{StatusCode: 200}, nil)
+        return id
+    }
+    base, evicted := add(), add()
+    r.Pin(base, base) // pins nest
+    for i := 0; i < 2*trafficBuffer; i++ {
+        add()
+    }
+    r.Unpin(base)
+    recent := add()
+    r.Keep(base, evicted, recent)
+    r.Unpin(base)
+    if len(r.order) != trafficBuffer {
+        t.Errorf("%d exchanges buffered after the last unpin, want %d", len(r.order), trafficBuffer)
+    }
+    for i := 0; i < trafficBuffer; i++ {
+        add()
+    }
+    r.Keep(recent) // gone by now
+    if len(r.written) != 0 || len(r.pins) != 0 {
+        t.Errorf("bookkeeping left after eviction: %d written, %d pins", len(r.written), len(r.pins))
+    }
+    if err := r.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    fp, err := os.Open(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fp.Close()
+    var got []uint64
+    sc := bufio.NewScanner(fp)
+    for sc.Scan() {
+        var ex Exchange
+        if err := json.Unmarshal(sc.Bytes(), &ex); err != nil {
+            t.Fatal(err)
+        }
+        got = append(got, ex.ID)
+    }
+    if len(got) != 2 || got[0] != base || got[1] != recent {
+        t.Errorf("written exchanges = %v, want [%d %d] (the pinned baseline survives, the unpinned one of the same age does not)", got, base, recent)
+    }
+}
+
+func TestNewRejectsRawModeBeforeCreatingTrafficFile(t *testing.T) {
+    name := filepath.Join(t.TempDir(), "traffic.har")
+    if _, err := New(&config.Options{RawHTTP: "sometimes", TrafficFile: name}); err == nil {
+        t.Fatal("New accepted an invalid raw mode")
+    }
+    if _, err := os.Stat(name); !os.IsNotExist(err) {
+        t.Errorf("traffic file left behind: %v", err)
+    }
+}
@@ -0,0 +1,79 @@
+package httpx
+
+import "strings"
+
+// Redactor replaces the values of secret headers, for the traffic log and for the
+// requests, reproduction snippets and response snapshots a finding carries. Cookie
+// names and the Authorization scheme are kept, as they help to tell requests apart.
+// A nil Redactor leaves headers as they are.
+type Redactor struct {
+    names map[string]bool // lower-cased header names
+}
+
+// NewRedactor redacts Cookie, Set-Cookie, Authorization and Proxy-Authorization plus
+// the extra header names.
+func NewRedactor(extra []string) *Redactor {
+    r := &Redactor{names: map[string]bool{"cookie": true, "set-cookie": true, "authorization": true, "proxy-authorization": true}}
+    r.Add(extra...)
+    return r
+}
+
+// Add redacts further header names.
+func (r *Redactor) Add(names ...string) {
+    if r == nil {
+        return
+    }
+    for _, n := range names {
+        if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+            r.names[n] = true
+        }
+    }
+}
+
+// Headers returns a copy of hs with secret values replaced.
+func (r *Redactor) Headers(hs []Header) []Header {
+    out := make([]Header, len(hs))
+    for i, h := range hs {
+        out[i] = h
+        name := strings.ToLower(h.Name)
+        if r == nil || !r.names[name] {
+            continue
+        }
+        switch name {
+        case "cookie":
+            parts := strings.Split(h.Value, ";")
+            for j, p := range parts {
+                if k, _, ok := strings.Cut(p, "="); ok {
+                    parts[j] = k + "=[redacted]"
+                }
+            }
+            out[i].Value = strings.Join(parts, ";")
+        case "set-cookie":
+            k, rest, _ := strings.Cut(h.Value, "=")
+            attrs := ""
+            if j := strings.Index(rest, ";"); j >= 0 {
+                attrs = rest[j:]
+            }
+            out[i].Value = k + "=[redacted]" + attrs
+        case "authorization", "proxy-authorization":
+            if scheme, _, ok := strings.Cut(h.Value, " "); ok {
+                out[i].Value = scheme + " [redacted]"
+            } else {
+                out[i].Value = "[redacted]"
+            }
+        default:
+            out[i].Value = "[redacted]"
+        }
+    }
+    return out
+}
+
+// Request returns a copy of s with secret header values replaced (s itself when r is nil).
+func (r *Redactor) Request(s *SentRequest) *SentRequest {
+    if r == nil || s == nil {
+        return s
+    }
+    c := *s
+    c.Headers = r.Headers(s.Headers)
+    return &c
+}
@@ -0,0 +1,78 @@
+package httpx
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+)
+
+func TestRedactorHeaders(t *testing.T) {
+    in := []Header{
+        {"Host", "example.com"},
+        {"Cookie", "sid=abc; theme=dark"},
+        {"Set-Cookie", "sid=abc; Path=/; HttpOnly"},
+        {"Authorization", "Bearer tok"},
+        {"proxy-authorization", "opaque"},
+        {"X-Api-Key", "k"},
+    }
+    want := []Header{
+        {"Host", "example.com"},
+        {"Cookie", "sid=[redacted]; theme=[redacted]"},
+        {"Set-Cookie", "sid=[redacted]; Path=/; HttpOnly"},
+        {"Authorization", "Bearer [redacted]"},
+        {"proxy-authorization", "[redacted]"},
+        {"X-Api-Key", "[redacted]"},
+    }
+    if got := NewRedactor([]string{" x-api-key "}).Headers(in); !reflect.DeepEqual(got, want) {
+        t.Errorf("Headers =\n%v\nwant\n%v", got, want)
+    }
+    if in[1].Value != "sid=abc; theme=dark" {
+        t.Errorf("input modified: %v", in[1])
+    }
+
+    var nilRedactor *Redactor
+    nilRedactor.Add("x")
+    if got := nilRedactor.Headers(in); !reflect.DeepEqual(got, in) {
+        t.Errorf("nil Redactor changed headers: %v", got)
+    }
+}
+
+func TestRedactorRequest(t *testing.T) {
+    s := &SentRequest{Method: "GET", Target: "/", Proto: "HTTP/1.1", Headers: []Header{{"Authorization", "Basic dTpw"}}}
+    got := NewRedactor(nil).Request(s)
+    if raw := got.Raw(); strings.Contains(raw, "dTpw") || !strings.Contains(raw, "Authorization: Basic [redacted]") {
+        t.Errorf("Raw = %q", raw)
+    }
+    if curl := got.Curl("https://h"); strings.Contains(curl, "dTpw") {
+        t.Errorf("Curl leaks the credentials: %s", curl)
+    }
+    if s.Headers[0].Value != "Basic dTpw" {
+        t.Errorf("original request modified: %v", s.Headers)
+    }
+    var nilRedactor *Redactor
+    if nilRedactor.Request(s) != s {
+        t.Errorf("nil Redactor should return the request itself")
+    }
+}
+
+func TestClientRedactor(t *testing.T) {
+    c, err := New(&config.Options{Timeout: time.Second, BearerToken: "tok", TrafficRedact: []string{"X-Token"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    got := c.Redactor().Headers([]Header{{"Authorization", "Bearer tok"}, {"X-Token", "t"}})
+    if got[0].Value != "Bearer [redacted]" || got[1].Value != "[redacted]" {
+        t.Errorf("redacted = %v", got)
+    }
+
+    c, err = New(&config.Options{Timeout: time.Second, BearerToken: "tok", NoRedact: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.Redactor() != nil {
+        t.Errorf("NoRedact: want a nil Redactor")
+    }
+}
@@ -0,0 +1,120 @@
+package httpx
+
+import (
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// skipRepro lists headers the curl command leaves to curl: the body length is computed
+// again, and curl manages the connection itself.
+var skipRepro = map[string]bool{"content-length": true, "connection": true}
+
+// Curl returns a curl command that sends s to origin (scheme://host[:port]) with the
+// request target byte for byte: --request-target carries the raw target and
+// --path-as-is stops curl from squashing dot segments in the URL.
+func (s *SentRequest) Curl(origin string) string {
+    u, err := url.Parse(origin)
+    if err != nil {
+        return ""
+    }
+    args := []string{"curl", "--path-as-is", "-i", "-sS"}
+    switch s.Proto {
+    case "HTTP/1.0":
+        args = append(args, "--http1.0")
+    case "HTTP/2.0":
+        if u.Scheme == "http" {
+            args = append(args, "--http2-prior-knowledge")
+        } else {
+            args = append(args, "--http2")
+        }
+    default:
+        args = append(args, "--http1.1")
+    }
+    if u.Scheme == "https" {
+        args = append(args, "-k")
+    }
+    if s.Method != "GET" || s.Body != "" {
+        args = append(args, "-X", shellQuote(s.Method))
+    }
+    for _, h := range s.Headers {
+        if skipRepro[strings.ToLower(h.Name)] || (strings.EqualFold(h.Name, "Host") && h.Value == u.Host) {
+            continue
+        }
+        args = append(args, "-H", shellQuote(h.Name+": "+h.Value))
+    }
+    if s.Host != "" && s.Host != u.Host && headerValue(s.Headers, "Host") == "" {
+        args = append(args, "-H", shellQuote("Host: "+s.Host))
+    }
+    if s.Body != "" {
+        args = append(args, "--data-binary", shellQuote(s.Body))
+    }
+    args = append(args, "--request-target", shellQuote(s.Target), shellQuote(u.Scheme+"://"+u.Host+"/"))
+    return strings.Join(args, " ")
+}
+
+// Python returns a Python 3 script that writes the request bytes of s to origin over a
+// plain or TLS socket, so the target, header order and casing reach the server exactly
+// as pohek sent them (http.client would refuse or rewrite many traversal targets), and
+// prints the response. HTTP/2 requests are replayed as HTTP/1.1.
+func (s *SentRequest) Python(origin string) string {
+    u, err := url.Parse(origin)
+    if err != nil {
+        return ""
+    }
+    port := u.Port()
+    if port == "" {
+        port = "80"
+        if u.Scheme == "https" {
+            port = "443"
+        }
+    }
+    req := *s
+    var b strings.Builder
+    b.WriteString("import http.client, socket, ssl\n\n")
+    if s.Proto == "HTTP/2.0" {
+        b.WriteString("# originally sent over HTTP/2; replayed as HTTP/1.1\n")
+        req.Proto = "HTTP/1.1"
+        if headerValue(s.Headers, "Host") == "" {
+            h := s.Host
+            if h == "" {
+                h = u.Host
+            }
+            req.Headers = append([]Header{{Name: "Host", Value: h}}, s.Headers...)
+        }
+    }
+    fmt.Fprintf(&b, "request = %s\n", pyBytes(req.Raw()))
+    fmt.Fprintf(&b, "sock = socket.create_connection((%s, %s), timeout=10)\n", strconv.Quote(u.Hostname()), port)
+    if u.Scheme == "https" {
+        fmt.Fprintf(&b, "sock = ssl._create_unverified_context().wrap_socket(sock, server_hostname=%s)\n", strconv.Quote(u.Hostname()))
+    }
+    b.WriteString("sock.sendall(request)\n")
+    fmt.Fprintf(&b, "resp = http.client.HTTPResponse(sock, method=%s)\n", strconv.Quote(s.Method))
+    b.WriteString("resp.begin()\nprint(resp.status, resp.reason)\nfor k, v in resp.getheaders():\n    print(f\"{k}: {v}\")\nprint()\nprint(resp.read(4096).decode(\"utf-8\", \"replace\"))\nsock.close()\n")
+    return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// pyBytes renders s as a Python bytes literal, escaping everything but printable ASCII.
+func pyBytes(s string) string {
+    var b strings.Builder
+    b.WriteString(`b"`)
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; {
+        case c == '"' || c == '\\':
+            b.WriteByte('\\')
+            b.WriteByte(c)
+        case c >= 0x20 && c < 0x7f:
+            b.WriteByte(c)
+        default:
+            fmt.Fprintf(&b, `\x%02x`, c)
+        }
+    }
+    b.WriteByte('"')
+    return b.String()
+}
@@ -0,0 +1,137 @@
+package httpx
+
+import (
+    "io"
+    "net"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestShellQuote(t *testing.T) {
+    tests := map[string]string{
+        "":            `''`,
+        "/a b":        `'/a b'`,
+        "it's":        `'it'\''s'`,
+        "$(id) `x` \\": "'$(id) `x` \\'",
+    }
+    for in, want := range tests {
+        if got := shellQuote(in); got != want {
+            t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+        }
+    }
+}
+
+func TestPyBytes(t *testing.T) {
+    tests := map[string]string{
+        "GET / HTTP/1.1\r\n": `b"GET / HTTP/1.1\x0d\x0a"`,
+        `a"b\c`:              `b"a\"b\\c"`,
+        "caf\xc3\xa9 \x00\x7f": `b"caf\xc3\xa9 \x00\x7f"`,
+    }
+    for in, want := range tests {
+        if got := pyBytes(in); got != want {
+            t.Errorf("pyBytes(%q) = %s, want %s", in, got, want)
+        }
+    }
+}
+
+func TestCurl(t *testing.T) {
+    s := &SentRequest{
+        Method:  "POST",
+        Target:  "/a b/..;/it's",
+        Proto:   "HTTP/1.1",
+        Host:    "example.com",
+        Headers: []Header{{Name: "Host", Value: "example.com"}, {Name: "X-Q", Value: "'x'"}, {Name: "Content-Length", Value: "2"}},
+        Body:    "{}",
+    }
+    want := `curl --path-as-is -i -sS --http1.1 -k -X 'POST' -H 'X-Q: '\''x'\''' --data-binary '{}' --request-target '/a b/..;/it'\''s' 'https://example.com/'`
+    if got := s.Curl("https://example.com"); got != want {
+        t.Errorf("Curl =\n%s\nwant\n%s", got, want)
+    }
+    s.Host = "internal"
+    s.Headers = []Header{{Name: "Host", Value: "internal"}}
+    s.Method, s.Body, s.Proto = "GET", "", "HTTP/2.0"
+    want = `curl --path-as-is -i -sS --http2-prior-knowledge -H 'Host: internal' --request-target '/a b/..;/it'\''s' 'http://10.0.0.1:8080/'`
+    if got := s.Curl("http://10.0.0.1:8080"); got != want {
+        t.Errorf("Curl =\n%s\nwant\n%s", got, want)
+    }
+}
+
+// TestPythonSendsExactBytes runs the generated script against a local listener and
+// compares what arrives with the request as pohek sent it.
+func TestPythonSendsExactBytes(t *testing.T) {
+    python, err := exec.LookPath("python3")
+    if err != nil {
+        t.Skip("python3 not installed")
+    }
+    tests := []struct {
+        name string
+        req  *SentRequest
+        want string // expected bytes; Raw() when empty
+    }{
+        {"odd target", &SentRequest{
+            Method:  "GET",
+            Target:  "/a b/..;/caf\xc3\xa9/\"q\"\\x",
+            Proto:   "HTTP/1.1",
+            Headers: []Header{{Name: "host", Value: "h"}, {Name: "X-Lower-first", Value: "1"}, {Name: "accept", Value: "*/*"}},
+        }, ""},
+        {"body and HTTP/1.0", &SentRequest{
+            Method:  "POST",
+            Target:  "/x",
+            Proto:   "HTTP/1.0",
+            Headers: []Header{{Name: "Host", Value: "h"}, {Name: "Content-Length", Value: "5"}},
+            Body:    "a\x00b\r\n",
+        }, ""},
+        {"HTTP/2 replayed as HTTP/1.1", &SentRequest{
+            Method:  "GET",
+            Target:  "/x",
+            Proto:   "HTTP/2.0",
+            Host:    "h2.example",
+            Headers: []Header{{Name: "user-agent", Value: "pohek"}},
+        }, "GET /x HTTP/1.1\r\nHost: h2.example\r\nuser-agent: pohek\r\n\r\n"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            want := tt.want
+            if want == "" {
+                want = tt.req.Raw()
+            }
+            ln, err := net.Listen("tcp", "127.0.0.1:0")
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer ln.Close()
+            got := make(chan string, 1)
+            go func() {
+                c, err := ln.Accept()
+                if err != nil {
+                    got <- ""
+                    return
+                }
+                defer c.Close()
+                c.SetDeadline(time.Now().Add(5 * time.Second))
+                buf := make([]byte, len(want))
+                n, _ := io.ReadFull(c, buf)
+                got <- string(buf[:n])
+                io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+            }()
+            script := filepath.Join(t.TempDir(), "repro.py")
+            if err := os.WriteFile(script, []byte(tt.req.Python("http://"+ln.Addr().String())), 0o644); err != nil {
+                t.Fatal(err)
+            }
+            out, err := exec.Command(python, script).CombinedOutput()
+            if err != nil {
+                t.Fatalf("script failed: %v\n%s", err, out)
+            }
+            if g := <-got; g != want {
+                t.Errorf("server received\n%q\nwant\n%q", g, want)
+            }
+            if !strings.HasPrefix(string(out), "200 OK\n") || !strings.HasSuffix(string(out), "ok\n") {
+                t.Errorf("script output = %q", out)
+            }
+        })
+    }
+}
@@ -0,0 +1,78 @@
+package httpx
+
+import (
+    "strings"
+    "time"
+)
+
+// RedirectPolicy selects how a single request treats redirects.
+type RedirectPolicy int
+
+const (
+    RedirectDefault RedirectPolicy = iota // use the client's configured policy
+    RedirectFollow                        // follow redirects (net/http transport only)
+    RedirectNone                          // return the redirect response itself
+)
+
+// RequestOptions overrides client defaults for a single request. The zero value keeps
+// every default, so modules can vary requests without touching shared client state.
+type RequestOptions struct {
+    Method    string         // HTTP method; client default when empty
+    Headers   []Header       // added to the client headers; same-named defaults are replaced
+    Body      []byte         // request body; Content-Length is computed by the transport
+    Redirects RedirectPolicy // per-request redirect policy
+    Timeout   time.Duration  // per-request timeout; client default when zero
+    Host      string         // Host header override; the connection still goes to baseURL
+    Raw       string         // RawAuto, RawAlways or RawNever; client default when empty
+    Proto     string         // ProtoAuto, ProtoHTTP10, ProtoHTTP11, ProtoH2 or ProtoH2C; client default when empty
+}
+
+// Merge returns a copy of o with the non-zero fields of over applied on top.
+// Headers of o that over also sets (names compared case-insensitively) are dropped and
+// over's headers appended, so repeated headers within one side are kept.
+// Either side may be nil.
+func (o *RequestOptions) Merge(over *RequestOptions) *RequestOptions {
+    out := &RequestOptions{}
+    if o != nil {
+        *out = *o
+        out.Headers = append([]Header(nil), o.Headers...)
+    }
+    if over == nil {
+        return out
+    }
+    if over.Method != "" {
+        out.Method = over.Method
+    }
+    if len(over.Headers) > 0 {
+        redefined := make(map[string]bool, len(over.Headers))
+        for _, h := range over.Headers {
+            redefined[strings.ToLower(h.Name)] = true
+        }
+        kept := out.Headers[:0]
+        for _, h := range out.Headers {
+            if !redefined[strings.ToLower(h.Name)] {
+                kept = append(kept, h)
+            }
+        }
+        out.Headers = append(kept, over.Headers...)
+    }
+    if over.Body != nil {
+        out.Body = over.Body
+    }
+    if over.Redirects != RedirectDefault {
+        out.Redirects = over.Redirects
+    }
+    if over.Timeout != 0 {
+        out.Timeout = over.Timeout
+    }
+    if over.Host != "" {
+        out.Host = over.Host
+    }
+    if over.Raw != "" {
+        out.Raw = over.Raw
+    }
+    if over.Proto != "" {
+        out.Proto = over.Proto
+    }
+    return out
+}
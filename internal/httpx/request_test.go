@@ -0,0 +1,70 @@
+package httpx
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestRequestOptionsMerge(t *testing.T) {
+    base := &RequestOptions{
+        Method:  "POST",
+        Headers: []Header{{Name: "X-Token", Value: "a"}, {Name: "Accept", Value: "*/*"}, {Name: "x-multi", Value: "1"}, {Name: "X-Multi", Value: "2"}},
+        Body:    []byte("body"),
+        Host:    "template.example",
+        Proto:   ProtoHTTP10,
+    }
+    tests := []struct {
+        name string
+        o    *RequestOptions
+        over *RequestOptions
+        want *RequestOptions
+    }{
+        {name: "both nil", want: &RequestOptions{}},
+        {
+            name: "nil over copies",
+            o:    base,
+            want: base,
+        },
+        {
+            name: "nil base",
+            over: &RequestOptions{Method: "GET", Headers: []Header{{Name: "A", Value: "1"}}},
+            want: &RequestOptions{Method: "GET", Headers: []Header{{Name: "A", Value: "1"}}},
+        },
+        {
+            name: "same-named headers replaced case-insensitively",
+            o:    base,
+            over: &RequestOptions{Headers: []Header{{Name: "x-token", Value: "b"}, {Name: "X-MULTI", Value: "3"}, {Name: "X-New", Value: "n"}}},
+            want: &RequestOptions{
+                Method:  "POST",
+                Headers: []Header{{Name: "Accept", Value: "*/*"}, {Name: "x-token", Value: "b"}, {Name: "X-MULTI", Value: "3"}, {Name: "X-New", Value: "n"}},
+                Body:    []byte("body"),
+                Host:    "template.example",
+                Proto:   ProtoHTTP10,
+            },
+        },
+        {
+            name: "repeated override headers kept",
+            o:    &RequestOptions{Headers: []Header{{Name: "Cookie", Value: "a=1"}}},
+            over: &RequestOptions{Headers: []Header{{Name: "Cookie", Value: "b=2"}, {Name: "cookie", Value: "c=3"}}},
+            want: &RequestOptions{Headers: []Header{{Name: "Cookie", Value: "b=2"}, {Name: "cookie", Value: "c=3"}}},
+        },
+        {
+            name: "scalar fields",
+            o:    base,
+            over: &RequestOptions{Method: "PUT", Body: []byte{}, Redirects: RedirectNone, Timeout: time.Second, Host: "h", Raw: RawAlways, Proto: ProtoH2},
+            want: &RequestOptions{Method: "PUT", Headers: base.Headers, Body: []byte{}, Redirects: RedirectNone, Timeout: time.Second, Host: "h", Raw: RawAlways, Proto: ProtoH2},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := tt.o.Merge(tt.over)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Merge =\n%+v\nwant\n%+v", got, tt.want)
+            }
+        })
+    }
+    if len(base.Headers) != 4 || base.Headers[0].Value != "a" {
+        t.Errorf("Merge modified its receiver: %+v", base.Headers)
+    }
+}
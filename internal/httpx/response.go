@@ -0,0 +1,205 @@
+package httpx
+
+import (
+    "bytes"
+    "crypto/tls"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Response is a minimal, serializable representation of an HTTP response
+// used by scanner and detectors. It intentionally avoids exposing net/http internals.
+// RequestURL is the absolute URL of the final request (after redirects, if followed);
+// Request is the first request exactly as it was written to the connection.
+//
+// Headers holds every response header. With the raw socket transport they are in
+// received order with original casing (HeadersOrdered is true); net/http canonicalizes
+// names and loses order, so they are sorted by name instead.
+//
+// Body holds at most the configured maximum of decoded bytes; BodyTruncated tells that
+// more followed. BodySize is the full decoded size when known (untruncated, or hashed
+// with BodySHA256), otherwise the size of the kept prefix.
+type Response struct {
+    Server         string       `json:"server"`
+    ContentType    string       `json:"content_type"`
+    StatusCode     int          `json:"status"`
+    Proto          string       `json:"proto"`
+    Headers        []Header     `json:"headers"`
+    HeadersOrdered bool         `json:"headers_ordered"`
+    Location       string       `json:"location,omitempty"`
+    ContentLength  int64        `json:"content_length"` // from the header; -1 when absent
+    SetCookieNames []string     `json:"set_cookie_names,omitempty"`
+    Via            string       `json:"via,omitempty"`
+    XCache         string       `json:"x_cache,omitempty"`
+    XPoweredBy     string       `json:"x_powered_by,omitempty"`
+    Elapsed        int64        `json:"elapsed_ms"` // request start to end of body
+    TLS            *TLSInfo     `json:"tls,omitempty"`
+    Redirects      []Hop        `json:"redirects,omitempty"`
+    Body           []byte       `json:"-"`
+    BodySize       int64        `json:"body_size"`
+    BodyTruncated  bool         `json:"body_truncated,omitempty"`
+    BodySHA256     string       `json:"body_sha256,omitempty"`
+    Encoding       string       `json:"content_encoding,omitempty"` // as sent by the server
+    Decoded        bool         `json:"decoded,omitempty"`
+    DecodeError    string       `json:"decode_error,omitempty"`
+    RequestURL     string       `json:"url"`
+    Request        *SentRequest `json:"request,omitempty"`
+    ExchangeID     uint64       `json:"exchange_id,omitempty"` // traffic log entry; 0 when not recorded
+}
+
+// TLSInfo summarizes the TLS connection the response arrived on.
+type TLSInfo struct {
+    Version  string   `json:"version"`
+    Cipher   string   `json:"cipher"`
+    ALPN     string   `json:"alpn,omitempty"`
+    Subject  string   `json:"subject,omitempty"` // leaf certificate subject
+    Issuer   string   `json:"issuer,omitempty"`
+    DNSNames []string `json:"dns_names,omitempty"`
+}
+
+// Hop is one redirect that was followed before the final response.
+type Hop struct {
+    URL      string `json:"url"`
+    Status   int    `json:"status"`
+    Location string `json:"location"`
+}
+
+// Header returns the first value of the named response header (case-insensitive).
+func (r *Response) Header(name string) string {
+    for _, h := range r.Headers {
+        if strings.EqualFold(h.Name, name) {
+            return h.Value
+        }
+    }
+    return ""
+}
+
+// Values returns every value of the named response header (case-insensitive).
+func (r *Response) Values(name string) []string {
+    var out []string
+    for _, h := range r.Headers {
+        if strings.EqualFold(h.Name, name) {
+            out = append(out, h.Value)
+        }
+    }
+    return out
+}
+
+// Raw renders the response as status line, headers and the kept body, with CRLF line
+// endings. The body is the decoded one, so it may not match Content-Encoding or
+// Content-Length.
+func (r *Response) Raw() []byte {
+    var b bytes.Buffer
+    fmt.Fprintf(&b, "%s %d %s\r\n", r.Proto, r.StatusCode, http.StatusText(r.StatusCode))
+    for _, h := range r.Headers {
+        b.WriteString(h.Name)
+        b.WriteString(": ")
+        b.WriteString(h.Value)
+        b.WriteString("\r\n")
+    }
+    b.WriteString("\r\n")
+    b.Write(r.Body)
+    return b.Bytes()
+}
+
+// exchange carries what the transports know about a request besides the http.Response.
+type exchange struct {
+    url        string
+    sent       *SentRequest
+    start      time.Time
+    rawHeaders []Header // received order and casing; nil for net/http
+    redirects  []Hop
+}
+
+// newResponse fills the metadata of a Response from resp and the exchange; body is the
+// response body as read by the caller.
+func newResponse(resp *http.Response, ex *exchange, body bodyInfo) *Response {
+    out := &Response{
+        Server:        resp.Header.Get("Server"),
+        ContentType:   resp.Header.Get("Content-Type"),
+        StatusCode:    resp.StatusCode,
+        Proto:         resp.Proto,
+        Location:      resp.Header.Get("Location"),
+        ContentLength: -1,
+        Via:           resp.Header.Get("Via"),
+        XCache:        resp.Header.Get("X-Cache"),
+        XPoweredBy:    resp.Header.Get("X-Powered-By"),
+        Elapsed:       time.Since(ex.start).Milliseconds(),
+        Redirects:     ex.redirects,
+        Body:          body.data,
+        BodySize:      body.size,
+        BodyTruncated: body.truncated,
+        BodySHA256:    body.sha256,
+        Encoding:      body.encoding,
+        Decoded:       body.decoded,
+        DecodeError:   body.decodeErr,
+        RequestURL:    ex.url,
+        Request:       ex.sent,
+    }
+    if ex.rawHeaders != nil {
+        out.Headers = ex.rawHeaders
+        out.HeadersOrdered = true
+    } else {
+        out.Headers = sortedHeaders(resp.Header)
+    }
+    if cl := resp.Header.Get("Content-Length"); cl != "" {
+        out.ContentLength = resp.ContentLength
+    }
+    for _, sc := range resp.Header.Values("Set-Cookie") {
+        name := strings.TrimSpace(strings.SplitN(sc, "=", 2)[0])
+        if name != "" {
+            out.SetCookieNames = append(out.SetCookieNames, name)
+        }
+    }
+    if resp.TLS != nil {
+        out.TLS = tlsInfo(resp.TLS)
+    }
+    return out
+}
+
+func sortedHeaders(h http.Header) []Header {
+    keys := make([]string, 0, len(h))
+    for k := range h {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    out := make([]Header, 0, len(keys))
+    for _, k := range keys {
+        for _, v := range h[k] {
+            out = append(out, Header{Name: k, Value: v})
+        }
+    }
+    return out
+}
+
+func tlsInfo(cs *tls.ConnectionState) *TLSInfo {
+    info := &TLSInfo{
+        Version: tlsVersionName(cs.Version),
+        Cipher:  tls.CipherSuiteName(cs.CipherSuite),
+        ALPN:    cs.NegotiatedProtocol,
+    }
+    if len(cs.PeerCertificates) > 0 {
+        leaf := cs.PeerCertificates[0]
+        info.Subject = leaf.Subject.String()
+        info.Issuer = leaf.Issuer.String()
+        info.DNSNames = leaf.DNSNames
+    }
+    return info
+}
+
+func tlsVersionName(v uint16) string {
+    switch v {
+    case tls.VersionTLS10:
+        return "TLS1.0"
+    case tls.VersionTLS11:
+        return "TLS1.1"
+    case tls.VersionTLS12:
+        return "TLS1.2"
+    case tls.VersionTLS13:
+        return "TLS1.3"
+    }
+    return "unknown"
+}
@@ -0,0 +1,339 @@
+package httpx
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// SessionRecipe describes how to detect a dead session and how to log in again.
+// It is loaded from a JSON file, e.g.:
+//
+//  {
+//    "login_request": "login.req",
+//    "base_url": "https://app.example.com",
+//    "check": "/api/me",
+//    "logout": {"status": [401], "body": "session expired", "redirect": "/login"},
+//    "extract": [
+//      {"cookie": "SESSIONID"},
+//      {"header": "Authorization", "json": "data.access_token", "format": "Bearer {}"},
+//      {"header": "X-CSRF-Token", "regex": "csrf_token\":\"([^\"]+)"}
+//    ]
+//  }
+//
+// Every cookie set by the login response is kept automatically; extract rules add
+// values found in the body or response headers. Check is a page that needs the session:
+// a response matching the logout markers only counts as a logout when the check request
+// matches them too, since a scanned path may well answer 401 for reasons of its own.
+type SessionRecipe struct {
+    LoginRequest string        `json:"login_request"` // raw request file, relative to the recipe
+    BaseURL      string        `json:"base_url"`      // defaults to the template's Host
+    Check        string        `json:"check"`         // path requested on BaseURL to confirm a logout (default /)
+    Logout       LogoutMarkers `json:"logout"`
+    Extract      []ExtractRule `json:"extract"`
+}
+
+// LogoutMarkers decide whether a response means the session is gone. Any match counts.
+type LogoutMarkers struct {
+    Status   []int  `json:"status"`   // e.g. 401, 419
+    Body     string `json:"body"`     // regular expression matched against the body
+    Redirect string `json:"redirect"` // substring of a redirect Location, e.g. "/login"
+}
+
+// ExtractRule takes one value from the login response and stores it as a cookie or header.
+// The value comes from Regex (first group, or the whole match), JSON (dotted path such as
+// data.items.0.token), or FromHeader; with none of them, the cookie of the same name set
+// by the login response is required to be present.
+type ExtractRule struct {
+    Cookie     string `json:"cookie,omitempty"`
+    Header     string `json:"header,omitempty"`
+    Regex      string `json:"regex,omitempty"`
+    JSON       string `json:"json,omitempty"`
+    FromHeader string `json:"from_header,omitempty"`
+    Format     string `json:"format,omitempty"` // "{}" is replaced by the value, e.g. "Bearer {}"
+
+    re *regexp.Regexp
+}
+
+// credentials are the session values applied to every request, tagged with the login
+// generation that produced them.
+type credentials struct {
+    gen     int
+    cookies []Header
+    headers []Header
+}
+
+// ErrSessionLost is returned (wrapped) for requests whose response showed a logout when
+// the session could not be restored, so the logged-out response is never taken for the
+// target's own answer.
+var ErrSessionLost = errors.New("session lost")
+
+// Session check and login backoff timing.
+const (
+    sessionCheckTTL   = 10 * time.Second // a confirmed session is trusted this long
+    sessionBackoff    = time.Second      // wait after the first failed login, doubled per failure
+    sessionBackoffMax = time.Minute
+)
+
+// Session keeps an authenticated session alive: it logs in lazily before the first
+// request, confirms a suspected logout with the recipe's check request and re-runs the
+// login recipe, after which the failed request is retried once. Failed logins back off
+// exponentially, and requests fail with ErrSessionLost until a login succeeds.
+type Session struct {
+    client  *Client
+    recipe  SessionRecipe
+    tpl     *Template
+    baseURL string
+    check   string
+    body    *regexp.Regexp
+
+    mu       sync.Mutex // serializes logins and checks
+    creds    *credentials // nil until logged in, and after the session was found lost
+    aliveGen int          // generation last confirmed by a check request
+    aliveAt  time.Time
+    lastGen  int // generation of the latest successful login
+    failures int // consecutive failed logins
+    retryAt  time.Time
+    lastErr  error
+}
+
+// LoadSession reads a JSON recipe and its login request template.
+func LoadSession(name string, ssl bool) (*Session, error) {
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    s := &Session{}
+    if err := json.Unmarshal(data, &s.recipe); err != nil {
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    if s.recipe.LoginRequest == "" {
+        return nil, fmt.Errorf("%s: login_request is required", name)
+    }
+    reqFile := s.recipe.LoginRequest
+    if !filepath.IsAbs(reqFile) {
+        reqFile = filepath.Join(filepath.Dir(name), reqFile)
+    }
+    if s.tpl, err = LoadTemplate(reqFile); err != nil {
+        return nil, err
+    }
+    s.baseURL = strings.TrimRight(s.recipe.BaseURL, "/")
+    if s.baseURL == "" {
+        s.baseURL = s.tpl.BaseURL(ssl)
+    }
+    if s.check = s.recipe.Check; s.check == "" {
+        s.check = "/"
+    } else if !strings.HasPrefix(s.check, "/") {
+        s.check = "/" + s.check
+    }
+    if m := s.recipe.Logout; len(m.Status) == 0 && m.Body == "" && m.Redirect == "" {
+        return nil, fmt.Errorf("%s: at least one logout marker is required", name)
+    }
+    if s.recipe.Logout.Body != "" {
+        if s.body, err = regexp.Compile(s.recipe.Logout.Body); err != nil {
+            return nil, fmt.Errorf("%s: logout body: %w", name, err)
+        }
+    }
+    for i := range s.recipe.Extract {
+        r := &s.recipe.Extract[i]
+        if (r.Cookie == "") == (r.Header == "") {
+            return nil, fmt.Errorf("%s: extract rule %d needs exactly one of cookie or header", name, i)
+        }
+        if r.Regex != "" {
+            if r.re, err = regexp.Compile(r.Regex); err != nil {
+                return nil, fmt.Errorf("%s: extract rule %d: %w", name, i, err)
+            }
+        }
+    }
+    return s, nil
+}
+
+// loggedOut reports whether resp matches any logout marker.
+func (s *Session) loggedOut(resp *Response) bool {
+    m := s.recipe.Logout
+    for _, st := range m.Status {
+        if resp.StatusCode == st {
+            return true
+        }
+    }
+    if s.body != nil && s.body.Match(resp.Body) {
+        return true
+    }
+    if m.Redirect != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+        if strings.Contains(resp.Header("Location"), m.Redirect) {
+            return true
+        }
+    }
+    return false
+}
+
+// current returns the active credentials, logging in first if there are none.
+func (s *Session) current() (*credentials, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.creds == nil {
+        if err := s.loginLocked(); err != nil {
+            return nil, err
+        }
+    }
+    return s.creds, nil
+}
+
+// recover is called when a response sent with credentials of generation gen matched the
+// logout markers. It returns fresh credentials when the session was lost and restored
+// (or another worker already renewed it), nil when the check request shows the session
+// is still alive, and an error wrapping ErrSessionLost when it cannot be restored.
+// A burst of logged-out responses thus causes at most one check and one login.
+func (s *Session) recover(gen int) (*credentials, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.creds != nil && s.creds.gen != gen {
+        return s.creds, nil
+    }
+    if s.creds != nil {
+        if s.aliveGen == gen && time.Since(s.aliveAt) < sessionCheckTTL {
+            return nil, nil
+        }
+        resp, err := s.client.do(s.baseURL, s.check, &RequestOptions{Redirects: RedirectNone}, s.creds)
+        if err != nil {
+            return nil, fmt.Errorf("%w: check request: %v", ErrSessionLost, err)
+        }
+        if !s.loggedOut(resp) {
+            s.aliveGen, s.aliveAt = gen, time.Now()
+            return nil, nil
+        }
+        s.creds = nil
+        fmt.Printf("[session] logged out (check %s answered %d)\n", s.check, resp.StatusCode)
+    }
+    if err := s.loginLocked(); err != nil {
+        return nil, err
+    }
+    return s.creds, nil
+}
+
+// loginLocked logs in unless a failed login asked to wait; failures double the wait up to
+// sessionBackoffMax, so a dead login endpoint is not hammered by every worker. Errors wrap
+// ErrSessionLost. Callers hold s.mu.
+func (s *Session) loginLocked() error {
+    if now := time.Now(); now.Before(s.retryAt) {
+        return fmt.Errorf("%w: login retried in %s after %d failure(s): %v", ErrSessionLost, s.retryAt.Sub(now).Round(time.Second), s.failures, s.lastErr)
+    }
+    if err := s.login(); err != nil {
+        s.failures++
+        wait := sessionBackoff << (s.failures - 1)
+        if wait > sessionBackoffMax || wait <= 0 {
+            wait = sessionBackoffMax
+        }
+        s.retryAt, s.lastErr = time.Now().Add(wait), err
+        fmt.Printf("[session] login failed (%d in a row, next try in %s): %v\n", s.failures, wait, err)
+        return fmt.Errorf("%w: %v", ErrSessionLost, err)
+    }
+    s.failures, s.retryAt, s.lastErr = 0, time.Time{}, nil
+    return nil
+}
+
+// login runs the recipe and replaces the credentials. Callers hold s.mu.
+func (s *Session) login() error {
+    target, ro := s.tpl.Request(s.tpl.Path())
+    ro.Redirects = RedirectNone
+    resp, err := s.client.do(s.baseURL, target, ro, nil)
+    if err != nil {
+        return fmt.Errorf("login request: %w", err)
+    }
+
+    s.lastGen++
+    gen := s.lastGen
+    creds := &credentials{gen: gen}
+    for _, sc := range resp.Headers {
+        if !strings.EqualFold(sc.Name, "Set-Cookie") {
+            continue
+        }
+        pair := strings.TrimSpace(strings.SplitN(sc.Value, ";", 2)[0])
+        if name, value, ok := strings.Cut(pair, "="); ok {
+            creds.cookies = setHeader(creds.cookies, Header{Name: name, Value: value})
+        }
+    }
+    for i, r := range s.recipe.Extract {
+        value, ok := s.extract(r, resp, creds)
+        if !ok {
+            return fmt.Errorf("login response (status %d): extract rule %d found nothing", resp.StatusCode, i)
+        }
+        if r.Format != "" {
+            value = strings.ReplaceAll(r.Format, "{}", value)
+        }
+        if r.Cookie != "" {
+            creds.cookies = setHeader(creds.cookies, Header{Name: r.Cookie, Value: value})
+        } else {
+            creds.headers = setHeader(creds.headers, Header{Name: r.Header, Value: value})
+        }
+    }
+    s.creds = creds
+    fmt.Printf("[session] logged in (generation %d)\n", gen)
+    return nil
+}
+
+func (s *Session) extract(r ExtractRule, resp *Response, creds *credentials) (string, bool) {
+    switch {
+    case r.re != nil:
+        m := r.re.FindSubmatch(resp.Body)
+        if m == nil {
+            return "", false
+        }
+        if len(m) > 1 {
+            return string(m[1]), true
+        }
+        return string(m[0]), true
+    case r.JSON != "":
+        var v interface{}
+        if err := json.Unmarshal(resp.Body, &v); err != nil {
+            return "", false
+        }
+        return jsonPath(v, r.JSON)
+    case r.FromHeader != "":
+        v := resp.Header(r.FromHeader)
+        return v, v != ""
+    }
+    // plain cookie rule: the login response must have set it
+    for _, c := range creds.cookies {
+        if c.Name == r.Cookie {
+            return c.Value, true
+        }
+    }
+    return "", false
+}
+
+// jsonPath resolves a dotted path (object keys and array indexes) in a decoded JSON value.
+func jsonPath(v interface{}, path string) (string, bool) {
+    for _, key := range strings.Split(path, ".") {
+        switch node := v.(type) {
+        case map[string]interface{}:
+            var ok bool
+            if v, ok = node[key]; !ok {
+                return "", false
+            }
+        case []interface{}:
+            i, err := strconv.Atoi(key)
+            if err != nil || i < 0 || i >= len(node) {
+                return "", false
+            }
+            v = node[i]
+        default:
+            return "", false
+        }
+    }
+    switch val := v.(type) {
+    case string:
+        return val, true
+    case nil, map[string]interface{}, []interface{}:
+        return "", false
+    default:
+        return fmt.Sprint(val), true
+    }
+}
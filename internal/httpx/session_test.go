@@ -0,0 +1,194 @@
+package httpx
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "pohek/internal/config"
+)
+
+func TestJSONPath(t *testing.T) {
+    var doc interface{}
+    if err := json.Unmarshal([]byte(`{"data": {"token": "abc", "n": 42, "ok": true, "items": [{"id": "x"}, {"id": 7}], "null": null, "obj": {}}}`), &doc); err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        path string
+        want string
+        ok   bool
+    }{
+        {"data.token", "abc", true},
+        {"data.n", "42", true},
+        {"data.ok", "true", true},
+        {"data.items.0.id", "x", true},
+        {"data.items.1.id", "7", true},
+        {"data.items.2.id", "", false},
+        {"data.items.-1", "", false},
+        {"data.items.x", "", false},
+        {"data.missing", "", false},
+        {"data.null", "", false},
+        {"data.obj", "", false},
+        {"data.items", "", false},
+        {"data.token.deeper", "", false},
+    }
+    for _, tt := range tests {
+        got, ok := jsonPath(doc, tt.path)
+        if got != tt.want || ok != tt.ok {
+            t.Errorf("jsonPath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+        }
+    }
+}
+
+func TestSessionLoggedOut(t *testing.T) {
+    s := &Session{
+        recipe: SessionRecipe{Logout: LogoutMarkers{Status: []int{401, 419}, Redirect: "/login"}},
+        body:   regexp.MustCompile(`(?i)session expired`),
+    }
+    loc := func(v string) []Header { return []Header{{Name: "Location", Value: v}} }
+    tests := []struct {
+        name string
+        resp *Response
+        want bool
+    }{
+        {"200", &Response{StatusCode: 200, Body: []byte("hello")}, false},
+        {"401", &Response{StatusCode: 401}, true},
+        {"419", &Response{StatusCode: 419}, true},
+        {"403", &Response{StatusCode: 403}, false},
+        {"body marker", &Response{StatusCode: 200, Body: []byte("Your Session Expired")}, true},
+        {"login redirect", &Response{StatusCode: 302, Headers: loc("https://app/login?next=/x")}, true},
+        {"other redirect", &Response{StatusCode: 302, Headers: loc("/home")}, false},
+        {"location without redirect status", &Response{StatusCode: 200, Headers: loc("/login")}, false},
+    }
+    for _, tt := range tests {
+        if got := s.loggedOut(tt.resp); got != tt.want {
+            t.Errorf("%s: loggedOut = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+// sessionServer is an app whose /login hands out a new session cookie (or fails while
+// loginDown is set), whose /me and /private need the current cookie, and whose
+// /other-backend always answers 401.
+type sessionServer struct {
+    *httptest.Server
+    logins    int32
+    checks    int32
+    current   atomic.Value // string
+    loginDown int32
+}
+
+func newSessionServer(t *testing.T) *sessionServer {
+    ss := &sessionServer{}
+    ss.current.Store("")
+    ss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/login":
+            if atomic.LoadInt32(&ss.loginDown) == 1 {
+                w.WriteHeader(http.StatusBadGateway)
+                return
+            }
+            n := atomic.AddInt32(&ss.logins, 1)
+            sid := "s" + string(rune('0'+n))
+            ss.current.Store(sid)
+            http.SetCookie(w, &http.Cookie{Name: "SID", Value: sid})
+            return
+        case "/other-backend":
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        case "/me":
+            atomic.AddInt32(&ss.checks, 1)
+        }
+        if c, err := r.Cookie("SID"); err != nil || c.Value != ss.current.Load().(string) {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        w.Write([]byte("private"))
+    }))
+    t.Cleanup(ss.Close)
+    return ss
+}
+
+func newSessionClient(t *testing.T, ss *sessionServer) *Client {
+    dir := t.TempDir()
+    host := strings.TrimPrefix(ss.URL, "http://")
+    if err := os.WriteFile(filepath.Join(dir, "login.req"), []byte("POST /login HTTP/1.1\nHost: "+host+"\n\nuser=a"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    recipe := `{"login_request": "login.req", "check": "/me", "logout": {"status": [401]}, "extract": [{"cookie": "SID"}]}`
+    if err := os.WriteFile(filepath.Join(dir, "session.json"), []byte(recipe), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    c, err := New(&config.Options{SessionFile: filepath.Join(dir, "session.json"), Timeout: 5 * time.Second})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return c
+}
+
+func TestSessionRecoversLostSession(t *testing.T) {
+    ss := newSessionServer(t)
+    c := newSessionClient(t, ss)
+    resp, err := c.Do(ss.URL, "/private", nil)
+    if err != nil || resp.StatusCode != 200 {
+        t.Fatalf("first request: %v %v", resp, err)
+    }
+    ss.current.Store("expired") // server-side logout
+    resp, err = c.Do(ss.URL, "/private", nil)
+    if err != nil || resp.StatusCode != 200 {
+        t.Fatalf("request after logout: %v %v", resp, err)
+    }
+    if n := atomic.LoadInt32(&ss.logins); n != 2 {
+        t.Errorf("%d logins, want 2", n)
+    }
+}
+
+func TestSessionGenuine401IsNotALogout(t *testing.T) {
+    ss := newSessionServer(t)
+    c := newSessionClient(t, ss)
+    for i := 0; i < 5; i++ {
+        resp, err := c.Do(ss.URL, "/other-backend", nil)
+        if err != nil {
+            t.Fatalf("request %d: %v", i, err)
+        }
+        if resp.StatusCode != 401 {
+            t.Errorf("request %d: status %d, want the target's own 401", i, resp.StatusCode)
+        }
+    }
+    if n := atomic.LoadInt32(&ss.logins); n != 1 {
+        t.Errorf("%d logins, want 1 (a 401 with a live session must not log in again)", n)
+    }
+    if n := atomic.LoadInt32(&ss.checks); n != 1 {
+        t.Errorf("%d check requests, want 1 (a confirmed session is trusted for a while)", n)
+    }
+}
+
+func TestSessionFailedLoginReturnsErrorAndBacksOff(t *testing.T) {
+    ss := newSessionServer(t)
+    c := newSessionClient(t, ss)
+    if _, err := c.Do(ss.URL, "/private", nil); err != nil {
+        t.Fatal(err)
+    }
+    ss.current.Store("expired")
+    atomic.StoreInt32(&ss.loginDown, 1)
+    for i := 0; i < 5; i++ {
+        resp, err := c.Do(ss.URL, "/private", nil)
+        if !errors.Is(err, ErrSessionLost) {
+            t.Fatalf("request %d: got %v, %v; want ErrSessionLost instead of the logged-out response", i, resp, err)
+        }
+    }
+    if n := atomic.LoadInt32(&ss.logins); n != 1 {
+        t.Errorf("%d successful logins, want 1", n)
+    }
+    if c.session.failures != 1 {
+        t.Errorf("%d failed logins, want 1 (later requests must wait for the backoff)", c.session.failures)
+    }
+}
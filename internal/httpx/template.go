@@ -0,0 +1,230 @@
+package httpx
+
+import (
+    "bytes"
+    "fmt"
+    "net"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// Markers recognised inside raw request templates.
+// PathMarker may appear anywhere (request line, headers, body) and is replaced by the
+// scanned path. StarMarker (sqlmap style) is only honoured inside the request target,
+// because "*" is common in header values such as "Accept: */*".
+const (
+    PathMarker = "§PATH§"
+    StarMarker = "*"
+)
+
+// Header is a single header line. Name casing and order are kept as provided.
+type Header struct {
+    Name  string `json:"name"`
+    Value string `json:"value"`
+}
+
+// Template is a raw HTTP request loaded from a file (sqlmap -r / Burp "copy to file" style).
+// The request target is split at the injection marker: the part before the marker is the
+// path under test, the part after it is appended verbatim to every rendered request.
+// If the target has no marker, the injection point is the end of the path (before "?").
+type Template struct {
+    Method  string
+    Target  string
+    Proto   string // request-line version: HTTP/1.0, HTTP/1.1 or HTTP/2 (see Request)
+    Scheme  string // only set when the request line uses absolute-form
+    Host    string
+    Headers []Header // all headers except Host and Content-Length
+    Body    []byte
+
+    prefix string
+    suffix string
+}
+
+// LoadTemplate reads a single raw request from file.
+func LoadTemplate(name string) (*Template, error) {
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    t, err := ParseTemplate(data)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    return t, nil
+}
+
+// LoadTemplates loads one template from a file, or every regular file of a directory
+// (sorted by name) when name is a directory.
+func LoadTemplates(name string) ([]*Template, error) {
+    st, err := os.Stat(name)
+    if err != nil {
+        return nil, err
+    }
+    if !st.IsDir() {
+        t, err := LoadTemplate(name)
+        if err != nil {
+            return nil, err
+        }
+        return []*Template{t}, nil
+    }
+    entries, err := os.ReadDir(name)
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(entries))
+    for _, e := range entries {
+        if e.Type().IsRegular() {
+            names = append(names, e.Name())
+        }
+    }
+    sort.Strings(names)
+    out := make([]*Template, 0, len(names))
+    for _, n := range names {
+        t, err := LoadTemplate(filepath.Join(name, n))
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, t)
+    }
+    return out, nil
+}
+
+// ParseTemplate parses a raw HTTP/1.x request. Both CRLF and LF line endings are accepted.
+// Content-Length is dropped and recomputed when the request is sent, because the body may
+// change size once the marker is substituted.
+func ParseTemplate(data []byte) (*Template, error) {
+    // tolerate leading blank lines left by copy/paste; they would end the head at once
+    data = bytes.TrimLeft(data, "\r\n")
+    head, body := data, []byte(nil)
+    if i := bytes.Index(data, []byte("\r\n\r\n")); i >= 0 {
+        head, body = data[:i], data[i+4:]
+    } else if i := bytes.Index(data, []byte("\n\n")); i >= 0 {
+        head, body = data[:i], data[i+2:]
+    }
+    lines := strings.Split(strings.ReplaceAll(string(head), "\r\n", "\n"), "\n")
+    if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+        return nil, fmt.Errorf("empty request")
+    }
+
+    parts := strings.Fields(lines[0])
+    if len(parts) < 2 || len(parts) > 3 {
+        return nil, fmt.Errorf("malformed request line %q", lines[0])
+    }
+    t := &Template{Method: parts[0], Target: parts[1], Proto: "HTTP/1.1", Body: body}
+    if len(parts) == 3 {
+        t.Proto = parts[2]
+    }
+    if _, ok := templateProtos[t.Proto]; !ok {
+        return nil, fmt.Errorf("unsupported HTTP version %q (want HTTP/1.0, HTTP/1.1 or HTTP/2)", t.Proto)
+    }
+
+    for _, l := range lines[1:] {
+        if strings.TrimSpace(l) == "" {
+            continue
+        }
+        i := strings.Index(l, ":")
+        if i <= 0 {
+            return nil, fmt.Errorf("malformed header line %q", l)
+        }
+        name, value := l[:i], strings.TrimSpace(l[i+1:])
+        switch strings.ToLower(name) {
+        case "host":
+            t.Host = value
+        case "content-length":
+            // recomputed on send
+        default:
+            t.Headers = append(t.Headers, Header{Name: name, Value: value})
+        }
+    }
+
+    // absolute-form request target: GET http://host/path HTTP/1.1
+    if strings.HasPrefix(t.Target, "http://") || strings.HasPrefix(t.Target, "https://") {
+        u, err := url.Parse(strings.ReplaceAll(t.Target, PathMarker, ""))
+        if err != nil {
+            return nil, fmt.Errorf("malformed absolute request target: %w", err)
+        }
+        t.Scheme = u.Scheme
+        if t.Host == "" {
+            t.Host = u.Host
+        }
+        i := strings.Index(t.Target, "://") + 3
+        if j := strings.Index(t.Target[i:], "/"); j >= 0 {
+            t.Target = t.Target[i+j:]
+        } else {
+            t.Target = "/"
+        }
+    }
+    if t.Host == "" {
+        return nil, fmt.Errorf("request has no Host header")
+    }
+
+    switch {
+    case strings.Contains(t.Target, PathMarker):
+        i := strings.Index(t.Target, PathMarker)
+        t.prefix, t.suffix = t.Target[:i], t.Target[i+len(PathMarker):]
+    case strings.Contains(t.Target, StarMarker):
+        i := strings.Index(t.Target, StarMarker)
+        t.prefix, t.suffix = t.Target[:i], t.Target[i+len(StarMarker):]
+    default:
+        i := strings.Index(t.Target, "?")
+        if i < 0 {
+            i = len(t.Target)
+        }
+        t.prefix, t.suffix = t.Target[:i], t.Target[i:]
+    }
+    return t, nil
+}
+
+// templateProtos maps request-line versions to RequestOptions.Proto. HTTP/1.1, the
+// version of most saved requests, leaves the choice to the client (--proto); HTTP/2 is
+// what Burp writes for requests captured over h2, so it is sent as h2 over TLS.
+var templateProtos = map[string]string{
+    "HTTP/1.0": ProtoHTTP10,
+    "HTTP/1.1": "",
+    "HTTP/2":   ProtoH2,
+    "HTTP/2.0": ProtoH2,
+}
+
+// Path returns the part of the request target before the marker, i.e. the path under test.
+func (t *Template) Path() string {
+    if t.prefix == "" {
+        return "/"
+    }
+    return t.prefix
+}
+
+// BaseURL returns scheme://host for the template. The scheme comes from an absolute-form
+// request line if present; otherwise https is used when ssl is set or the Host header
+// names port 443.
+func (t *Template) BaseURL(ssl bool) string {
+    scheme := t.Scheme
+    if scheme == "" {
+        scheme = "http"
+        if _, port, err := net.SplitHostPort(t.Host); ssl || (err == nil && port == "443") {
+            scheme = "https"
+        }
+    }
+    return scheme + "://" + t.Host
+}
+
+// Request substitutes rawPath at the injection point and returns the request target and
+// the options (method, headers, Host, body, protocol) to send it with. PathMarker
+// occurrences in headers and body are replaced too. A template written as HTTP/1.0 or
+// HTTP/2 is sent with that protocol; HTTP/1.1 uses the client's default.
+func (t *Template) Request(rawPath string) (string, *RequestOptions) {
+    headers := make([]Header, len(t.Headers))
+    for i, h := range t.Headers {
+        headers[i] = Header{Name: h.Name, Value: strings.ReplaceAll(h.Value, PathMarker, rawPath)}
+    }
+    ro := &RequestOptions{
+        Method:  t.Method,
+        Headers: headers,
+        Body:    bytes.ReplaceAll(t.Body, []byte(PathMarker), []byte(rawPath)),
+        Host:    t.Host,
+        Proto:   templateProtos[t.Proto],
+    }
+    return rawPath + t.suffix, ro
+}
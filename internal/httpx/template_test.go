@@ -0,0 +1,157 @@
+package httpx
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+    tests := []struct {
+        name    string
+        raw     string
+        method  string
+        proto   string
+        host    string
+        path    string
+        target  string // rendered with "/x"
+        headers []Header
+        body    string
+        wantErr bool
+    }{
+        {
+            name:    "crlf with body",
+            raw:     "POST /api/v1?q=1 HTTP/1.1\r\nHost: example.com\r\nContent-Length: 3\r\nX-A: b\r\n\r\nabc",
+            method:  "POST",
+            proto:   "HTTP/1.1",
+            host:    "example.com",
+            path:    "/api/v1",
+            target:  "/x?q=1",
+            headers: []Header{{Name: "X-A", Value: "b"}},
+            body:    "abc",
+        },
+        {
+            name:   "lf endings and leading blank lines",
+            raw:    "\n\nGET /a HTTP/1.0\nHost: h:8080\n\n",
+            method: "GET",
+            proto:  "HTTP/1.0",
+            host:   "h:8080",
+            path:   "/a",
+            target: "/x",
+        },
+        {
+            name:    "path marker",
+            raw:     "GET /pre/§PATH§/post HTTP/1.1\nHost: h\nX-P: §PATH§\n",
+            method:  "GET",
+            proto:   "HTTP/1.1",
+            host:    "h",
+            path:    "/pre/",
+            target:  "/x/post",
+            headers: []Header{{Name: "X-P", Value: "§PATH§"}},
+        },
+        {
+            name:    "star marker only in the target",
+            raw:     "GET /a/*?b=1 HTTP/2\nHost: h\nAccept: */*\n",
+            method:  "GET",
+            proto:   "HTTP/2",
+            host:    "h",
+            path:    "/a/",
+            target:  "/x?b=1",
+            headers: []Header{{Name: "Accept", Value: "*/*"}},
+        },
+        {
+            name:   "absolute form without version",
+            raw:    "GET https://abs.example/p/q\n\n",
+            method: "GET",
+            proto:  "HTTP/1.1",
+            host:   "abs.example",
+            path:   "/p/q",
+            target: "/x",
+        },
+        {name: "empty", raw: "\n\n", wantErr: true},
+        {name: "no host", raw: "GET / HTTP/1.1\nX: y\n", wantErr: true},
+        {name: "malformed request line", raw: "GET\nHost: h\n", wantErr: true},
+        {name: "malformed header", raw: "GET / HTTP/1.1\nHost: h\nnocolon\n", wantErr: true},
+        {name: "unsupported version", raw: "GET / HTTP/3\nHost: h\n", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tpl, err := ParseTemplate([]byte(tt.raw))
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ParseTemplate: want error, got %+v", tpl)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("ParseTemplate: %v", err)
+            }
+            if tpl.Method != tt.method || tpl.Proto != tt.proto || tpl.Host != tt.host {
+                t.Errorf("method/proto/host = %q %q %q, want %q %q %q", tpl.Method, tpl.Proto, tpl.Host, tt.method, tt.proto, tt.host)
+            }
+            if got := tpl.Path(); got != tt.path {
+                t.Errorf("Path() = %q, want %q", got, tt.path)
+            }
+            target, ro := tpl.Request("/x")
+            if target != tt.target {
+                t.Errorf("Request target = %q, want %q", target, tt.target)
+            }
+            if !reflect.DeepEqual(tpl.Headers, tt.headers) {
+                t.Errorf("Headers = %v, want %v", tpl.Headers, tt.headers)
+            }
+            if string(ro.Body) != tt.body {
+                t.Errorf("body = %q, want %q", ro.Body, tt.body)
+            }
+        })
+    }
+}
+
+func TestTemplateRequestSubstitutesAndSetsProto(t *testing.T) {
+    tpl, err := ParseTemplate([]byte("POST /§PATH§ HTTP/1.0\nHost: h\nX-P: v=§PATH§\n\nbody §PATH§"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    target, ro := tpl.Request("..;/admin")
+    if target != "..;/admin" {
+        t.Errorf("target = %q", target)
+    }
+    if ro.Method != "POST" || ro.Host != "h" || ro.Proto != ProtoHTTP10 {
+        t.Errorf("options = %+v", ro)
+    }
+    if ro.Headers[0].Value != "v=..;/admin" || string(ro.Body) != "body ..;/admin" {
+        t.Errorf("marker not substituted: %+v %q", ro.Headers, ro.Body)
+    }
+    if tpl.Headers[0].Value != "v=§PATH§" {
+        t.Errorf("template headers modified: %+v", tpl.Headers)
+    }
+    for proto, want := range map[string]string{"HTTP/1.1": "", "HTTP/2": ProtoH2} {
+        tpl, err := ParseTemplate([]byte("GET / " + proto + "\nHost: h\n"))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if _, ro := tpl.Request("/"); ro.Proto != want {
+            t.Errorf("%s: Proto = %q, want %q", proto, ro.Proto, want)
+        }
+    }
+}
+
+func TestTemplateBaseURL(t *testing.T) {
+    tests := []struct {
+        raw  string
+        ssl  bool
+        want string
+    }{
+        {"GET / HTTP/1.1\nHost: h\n", false, "http://h"},
+        {"GET / HTTP/1.1\nHost: h\n", true, "https://h"},
+        {"GET / HTTP/1.1\nHost: h:443\n", false, "https://h:443"},
+        {"GET http://h:8443/ HTTP/1.1\n\n", true, "http://h:8443"},
+    }
+    for _, tt := range tests {
+        tpl, err := ParseTemplate([]byte(tt.raw))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := tpl.BaseURL(tt.ssl); got != tt.want {
+            t.Errorf("BaseURL(%v) for %q = %q, want %q", tt.ssl, tt.raw, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,95 @@
+package httpx
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+
+    "pohek/internal/config"
+)
+
+// tlsConfig builds the TLS configuration shared by every transport: certificate
+// validation (optional, NoTLSValidation), extra CAs trusted on top of the system pool,
+// a client certificate for mTLS and a fixed SNI.
+func tlsConfig(opt *config.Options) (*tls.Config, error) {
+    cfg := &tls.Config{InsecureSkipVerify: opt.NoTLSValidation, ServerName: opt.SNI}
+    if opt.CACert != "" {
+        pem, err := os.ReadFile(opt.CACert)
+        if err != nil {
+            return nil, fmt.Errorf("CA bundle: %w", err)
+        }
+        pool, err := x509.SystemCertPool()
+        if err != nil || pool == nil {
+            pool = x509.NewCertPool()
+        }
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("CA bundle %s: no PEM certificates found", opt.CACert)
+        }
+        cfg.RootCAs = pool
+    }
+    if opt.ClientCert != "" {
+        key := opt.ClientKey
+        if key == "" {
+            key = opt.ClientCert // certificate and key in one PEM file
+        }
+        cert, err := tls.LoadX509KeyPair(opt.ClientCert, key)
+        if err != nil {
+            return nil, fmt.Errorf("client certificate: %w", err)
+        }
+        cfg.Certificates = []tls.Certificate{cert}
+    } else if opt.ClientKey != "" {
+        return nil, fmt.Errorf("client key given without a client certificate")
+    }
+    return cfg, nil
+}
+
+// parseResolve parses curl-style "host:port:addr" overrides into a map from host:port to
+// the address to connect to instead. addr may be an IPv6 literal, with or without brackets.
+func parseResolve(entries []string) (map[string]string, error) {
+    out := make(map[string]string, len(entries))
+    for _, e := range entries {
+        parts := strings.SplitN(strings.TrimSpace(e), ":", 3)
+        if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+            return nil, fmt.Errorf("invalid resolve entry %q (want host:port:addr)", e)
+        }
+        addr := strings.Trim(parts[2], "[]")
+        if net.ParseIP(addr) == nil && strings.ContainsAny(addr, ":/") {
+            return nil, fmt.Errorf("invalid resolve address %q", parts[2])
+        }
+        out[net.JoinHostPort(strings.ToLower(parts[0]), parts[1])] = net.JoinHostPort(addr, parts[1])
+    }
+    return out, nil
+}
+
+// checkResolveProxy rejects overrides for host:port pairs that would be reached through
+// an HTTP(S) proxy: the proxy resolves and connects to the target itself, so the
+// override could not apply. SOCKS proxies are dialled with the override applied.
+func checkResolveProxy(overrides map[string]string, proxy func(*http.Request) (*url.URL, error)) error {
+    for hostPort := range overrides {
+        for _, scheme := range []string{"http", "https"} {
+            pu, err := proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: hostPort}})
+            if err == nil && pu != nil {
+                return fmt.Errorf("--resolve %s: %s requests go through proxy %s, which resolves the host itself (exclude it with NO_PROXY or drop the override)", hostPort, scheme, pu.Redacted())
+            }
+        }
+    }
+    return nil
+}
+
+// resolveDial wraps dial so that connections to overridden host:port pairs go to the
+// configured address. The hostname is still used for Host, SNI and certificate checks.
+// It sits in front of SOCKS dialling, so the proxy is asked for the overridden address.
+func resolveDial(overrides map[string]string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        if to, ok := overrides[strings.ToLower(addr)]; ok {
+            addr = to
+        }
+        return dial(ctx, network, addr)
+    }
+}
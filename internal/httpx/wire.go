@@ -0,0 +1,119 @@
+package httpx
+
+import (
+    "net/http"
+    "net/http/httptrace"
+    "net/url"
+    "strings"
+    "sync"
+)
+
+// SentRequest is the request as it was written to the connection: the request line,
+// the Host and every header in write order (including those added by the transport,
+// such as Accept-Encoding or Content-Length) and the body.
+type SentRequest struct {
+    Method  string   `json:"method"`
+    Target  string   `json:"target"`
+    Proto   string   `json:"proto"`
+    Host    string   `json:"host"`
+    Headers []Header `json:"headers"`
+    Body    string   `json:"body,omitempty"`
+}
+
+// RequestLine returns "METHOD target PROTO".
+func (s *SentRequest) RequestLine() string {
+    return s.Method + " " + s.Target + " " + s.Proto
+}
+
+// Raw renders the request as it appeared on the wire, with CRLF line endings,
+// suitable for pasting into a raw HTTP client.
+func (s *SentRequest) Raw() string {
+    var b strings.Builder
+    b.WriteString(s.RequestLine())
+    b.WriteString("\r\n")
+    for _, h := range s.Headers {
+        b.WriteString(h.Name)
+        b.WriteString(": ")
+        b.WriteString(h.Value)
+        b.WriteString("\r\n")
+    }
+    b.WriteString("\r\n")
+    b.WriteString(s.Body)
+    return b.String()
+}
+
+// wireRecorder collects header fields written by the transport for the first request
+// of an exchange. Fields written for redirect hops are ignored.
+type wireRecorder struct {
+    mu      sync.Mutex
+    done    bool
+    headers []Header
+}
+
+func (w *wireRecorder) trace() *httptrace.ClientTrace {
+    return &httptrace.ClientTrace{
+        WroteHeaderField: func(key string, values []string) {
+            w.mu.Lock()
+            defer w.mu.Unlock()
+            if w.done {
+                return
+            }
+            for _, v := range values {
+                w.headers = append(w.headers, Header{Name: key, Value: v})
+            }
+        },
+        WroteHeaders: func() {
+            w.mu.Lock()
+            w.done = true
+            w.mu.Unlock()
+        },
+    }
+}
+
+// sent builds the SentRequest for req from the recorded header fields.
+// proxied marks plain-HTTP requests sent through a forward proxy, which use absolute-form.
+func (w *wireRecorder) sent(req *http.Request, body []byte, proto string, proxied bool) *SentRequest {
+    w.mu.Lock()
+    headers := append([]Header(nil), w.headers...)
+    w.mu.Unlock()
+
+    host := req.Host
+    if host == "" {
+        host = req.URL.Host
+    }
+    target := req.URL.RequestURI()
+    if proxied && req.URL.Scheme == "http" {
+        target = req.URL.Scheme + "://" + req.URL.Host + target
+    }
+    // HTTP/2 carries the request line and Host as pseudo-header fields
+    fields := headers[:0]
+    for _, h := range headers {
+        switch h.Name {
+        case ":path":
+            target = h.Value
+        case ":authority":
+            host = h.Value
+        }
+        if !strings.HasPrefix(h.Name, ":") {
+            fields = append(fields, h)
+        }
+    }
+    headers = fields
+    return &SentRequest{
+        Method:  req.Method,
+        Target:  target,
+        Proto:   proto,
+        Host:    host,
+        Headers: headers,
+        Body:    string(body),
+    }
+}
+
+// requestURL returns the absolute URL of req. net/http's URL.String drops the host when
+// Opaque is set (https:/path), so raw-path requests are rebuilt from their parts.
+func requestURL(u *url.URL) string {
+    if u.Opaque == "" {
+        return u.String()
+    }
+    return u.Scheme + "://" + u.Host + u.Opaque
+}
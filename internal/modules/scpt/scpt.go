@@ -7,8 +7,10 @@ import (
     "net/url"
     "strings"
     "time"
+    "unicode/utf8"
 
     "pohek/helper"
+    "pohek/internal/detect"
     "pohek/internal/engine"
     "pohek/internal/httpx"
     "pohek/internal/output"
@@ -17,10 +19,22 @@ import (
 
 // Module implements secondary context path traversal scanning as a pluggable module.
 // It reuses shared dependencies (HTTP client, payload source, detector, sink) passed via engine.Deps.
-type Module struct{}
+// Protocols lists httpx protocols (e.g. "http1.1", "h2") to repeat every payload over,
+// each with its own baselines, since front-ends often normalize paths differently per
+// protocol; empty means the client's default protocol only.
+type Module struct {
+    Protocols []string
+}
 
 func (Module) Name() string { return "scpt" }
 
+func init() {
+    output.RuleDescriptions["scpt/status"] = "Traversal payload changes the status code compared with the parent and a non-existent path"
+    output.RuleDescriptions["scpt/server"] = "Traversal payload changes the Server header compared with the parent and a non-existent path"
+    output.RuleDescriptions["scpt/content_type"] = "Traversal payload changes the Content-Type compared with the parent and a non-existent path"
+    output.RuleDescriptions["scpt/headers"] = "Traversal payload changes the response header set compared with the parent and a non-existent path"
+}
+
 // Payloads returns the SCPT-specific payload source. Keeping a dedicated
 // instance allows other modules to use their own lists independently.
 func (Module) Payloads() *payload.Source {
@@ -52,19 +66,21 @@ func (Module) Preprocess(ctx context.Context, deps engine.Deps, t engine.Target,
     if cleaned == raw {
         return t, base, nil
     }
-    nb, nerr := deps.Client.Do(t.BaseURL, cleaned)
+    nt := t
+    nt.Path = cleaned
+    nb, nerr := deps.Fetch(nt, cleaned)
     if nerr != nil {
         // fall back to original baseline on error
-        return engine.Target{BaseURL: t.BaseURL, Path: cleaned}, base, nil
+        return nt, base, nil
     }
-    return engine.Target{BaseURL: t.BaseURL, Path: cleaned}, nb, nil
+    return nt, nb, nil
 }
 
 // Run performs SCT scanning for targets derived from the provided options and wordlist.
 // It builds multiple baselines (root/parent/dummy/nonexistent) to reduce false positives
 // and performs module-specific detection heuristics.
 // Process runs SCT payloads for a single target, using the provided base response as baseline.
-func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, base *httpx.Response) error {
+func (m Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, base *httpx.Response) error {
     fmt.Printf("[scpt] scanning %s%s\n", t.BaseURL, t.Path)
 
     // Pull the module-specific payload list
@@ -72,7 +88,27 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
     if len(payloads) == 0 {
         return nil
     }
+    if len(m.Protocols) == 0 {
+        return scan(ctx, deps, t, base, payloads, nil)
+    }
+    // Baselines are rebuilt per protocol: responses are only compared within one protocol
+    for _, proto := range m.Protocols {
+        ro := &httpx.RequestOptions{Proto: proto}
+        pbase, err := deps.FetchWith(t, t.Path, ro)
+        if err != nil {
+            fmt.Printf("[scpt] %s%s over %s: %v\n", t.BaseURL, t.Path, proto, err)
+            continue
+        }
+        if err := scan(ctx, deps, t, pbase, payloads, ro); err != nil {
+            return err
+        }
+    }
+    return nil
+}
 
+// scan runs the payloads against one target over a single protocol; ro carries the
+// per-request options (nil for client defaults) used for baselines and payloads alike.
+func scan(ctx context.Context, deps engine.Deps, t engine.Target, base *httpx.Response, payloads []string, ro *httpx.RequestOptions) error {
     // Normalize current path and compute parent and baselines once per target
     path := t.Path
     if path != "" && !strings.HasSuffix(path, "/") {
@@ -85,7 +121,7 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
     if back == "/" || strings.TrimSpace(back) == "" {
         backResp = base
     } else {
-        b, berr := deps.Client.Do(t.BaseURL, back)
+        b, berr := deps.FetchWith(t, back, ro)
         if berr != nil {
             return nil
         }
@@ -94,10 +130,16 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
 
     // Non-existent under parent context
     nonexistent := strings.TrimSuffix(back, "/") + "/gachimuchicheburek"
-    nonResp, err := deps.Client.Do(t.BaseURL, nonexistent)
+    nonResp, err := deps.FetchWith(t, nonexistent, ro)
     if err != nil {
         return nil
     }
+    backFP, nonFP := headerFingerprint(backResp), headerFingerprint(nonResp)
+    forced := forcedProto(deps, t, ro)
+    // keep the baselines a finding refers to in the findings-only traffic log
+    pinned := []uint64{base.ExchangeID, backResp.ExchangeID, nonResp.ExchangeID}
+    deps.Client.Recorder().Pin(pinned...)
+    defer deps.Client.Recorder().Unpin(pinned...)
 
     // Sequential per-payload scanning (engine handles target-level concurrency)
     for _, p := range payloads {
@@ -109,7 +151,7 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
         }
         retries := deps.Opts.Retry
         for attempt := 0; attempt <= retries; attempt++ {
-            resp, err := deps.Client.Do(t.BaseURL, travPath)
+            resp, err := deps.FetchWith(t, travPath, ro)
             if err != nil {
                 if attempt < retries {
                     continue
@@ -125,12 +167,16 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
             statusDiff := (resp.StatusCode != backResp.StatusCode) && (resp.StatusCode != nonResp.StatusCode)
             serverDiff := (resp.Server != backResp.Server) && (resp.Server != nonResp.Server)
             contentTypeDiff := (resp.ContentType != backResp.ContentType) && (resp.ContentType != nonResp.ContentType)
-            notes := make([]string, 0, 3)
+            hdrDiff := detect.DiffHeaders(headerFingerprint(resp), backFP, nonFP)
+            headersDiff := !hdrDiff.Empty()
+            notes := make([]string, 0, 4)
             if statusDiff { notes = append(notes, "Status code differs (vs parent & non-existent)") }
             if serverDiff { notes = append(notes, "Server header differs (vs parent & non-existent)") }
             if contentTypeDiff { notes = append(notes, "Content-Type differs (vs parent & non-existent)") }
-            if statusDiff || serverDiff || contentTypeDiff {
-                emitFinding(deps, t.BaseURL, path, p, resp, statusDiff, serverDiff, contentTypeDiff, notes)
+            if headersDiff { notes = append(notes, "Header set differs (vs parent & non-existent): "+hdrDiff.String()) }
+            if statusDiff || serverDiff || contentTypeDiff || headersDiff {
+                signals := map[string]bool{"status": statusDiff, "server": serverDiff, "content_type": contentTypeDiff, "headers": headersDiff}
+                emitFinding(deps, t.BaseURL, path, p, signals, hdrDiff, notes, forced, base, exchange{"traversal", resp}, exchange{"parent", backResp}, exchange{"nonexistent", nonResp})
             }
             break
         }
@@ -138,7 +184,40 @@ func (Module) Process(ctx context.Context, deps engine.Deps, t engine.Target, ba
     return nil
 }
 
-func emitFinding(deps engine.Deps, baseURL, path, payload string, resp *httpx.Response, statusDiff, serverDiff, contentTypeDiff bool, notes []string) {
+// forcedProto reports whether requests for t go out over a protocol the user chose
+// (--proto, --scpt-protos or the request file's version) rather than a negotiated one.
+func forcedProto(deps engine.Deps, t engine.Target, ro *httpx.RequestOptions) bool {
+    proto := deps.Opts.Protocol
+    if t.Template != nil {
+        _, tro := t.Template.Request(t.Path)
+        ro = tro.Merge(ro)
+    }
+    if ro != nil && ro.Proto != "" {
+        proto = ro.Proto
+    }
+    return proto != "" && proto != httpx.ProtoAuto
+}
+
+// exchange is a response with its role in a finding.
+type exchange struct {
+    role string
+    resp *httpx.Response
+}
+
+// headerFingerprint adapts a response to detect.Fingerprint.
+func headerFingerprint(resp *httpx.Response) detect.HeaderFingerprint {
+    names := make([]string, len(resp.Headers))
+    for i, h := range resp.Headers {
+        names[i] = h.Name
+    }
+    return detect.Fingerprint(names, resp.SetCookieNames, resp.HeadersOrdered)
+}
+
+// emitFinding reports the first of exs (the traversal hit) with the baselines it was
+// compared against; base is the target's own baseline, referenced for the traffic log.
+// forced tells whether the protocol was chosen by the user (see forcedProto).
+func emitFinding(deps engine.Deps, baseURL, path, payload string, signals map[string]bool, hdrDiff detect.HeaderDiff, notes []string, forced bool, base *httpx.Response, exs ...exchange) {
+    resp := exs[0].resp
     f := &output.Finding{
         Module:      "scpt",
         Timestamp:   time.Now(),
@@ -146,13 +225,104 @@ func emitFinding(deps engine.Deps, baseURL, path, payload string, resp *httpx.Re
         Path:        path,
         Payload:     payload,
         URL:         resp.RequestURL,
-        Signals:     map[string]bool{"status": statusDiff, "server": serverDiff, "content_type": contentTypeDiff},
+        Signals:     signals,
         Notes:       notes,
         Status:      resp.StatusCode,
         Server:      resp.Server,
         ContentType: resp.ContentType,
+        Protocol:    resp.Proto,
+        Confidence:  confidence(signals),
     }
+    f.ProtocolForced = forced
+    f.HeadersAdded, f.HeadersRemoved = hdrDiff.Changes()
+    f.HeadersCasing, f.HeadersReordered = hdrDiff.Casing, hdrDiff.OrderChanged
+    // secrets from --bearer, --cookie or the login session stay out of the finding;
+    // the evidence store keeps the raw exchanges
+    redact := deps.Client.Redactor()
+    seen := map[uint64]bool{0: true}
+    var diffs []string
+    for i, ex := range append(exs, exchange{"base", base}) {
+        if ex.resp == nil {
+            continue
+        }
+        if !seen[ex.resp.ExchangeID] {
+            seen[ex.resp.ExchangeID] = true
+            f.Exchanges = append(f.Exchanges, ex.resp.ExchangeID)
+        }
+        if i >= len(exs) {
+            continue
+        }
+        f.Responses = append(f.Responses, snapshot(ex.role, ex.resp, redact))
+        if i > 0 {
+            diffs = append(diffs, "vs "+ex.role+": "+detect.CompareBodies(resp.Body, ex.resp.Body).String())
+        }
+        if deps.Evidence != nil {
+            if ev, err := storeEvidence(deps.Evidence, ex); err == nil {
+                f.Evidence = append(f.Evidence, ev)
+            } else {
+                fmt.Printf("[scpt] cannot store evidence: %v\n", err)
+            }
+        }
+    }
+    f.BodyDiff = strings.Join(diffs, "; ")
+    if resp.Request != nil {
+        sent := redact.Request(resp.Request)
+        f.Request = sent.Raw()
+        f.Curl = sent.Curl(baseURL)
+        f.Python = sent.Python(baseURL)
+        f.Protocol = resp.Request.Proto // what was sent; servers often answer 1.0 with 1.1
+    }
+    f.Fingerprint = output.Fingerprint(f)
     _ = deps.Sink.Write(f)
 }
 
+// storeEvidence writes the raw request and response of ex to the evidence store.
+func storeEvidence(store *output.EvidenceStore, ex exchange) (output.Evidence, error) {
+    ev := output.Evidence{Role: ex.role}
+    var err error
+    if ex.resp.Request != nil {
+        if ev.Request, err = store.Put([]byte(ex.resp.Request.Raw()), ".req"); err != nil {
+            return ev, err
+        }
+    }
+    ev.Response, err = store.Put(ex.resp.Raw(), ".resp")
+    return ev, err
+}
+
+// snapshotBody is how much of a text body a finding keeps per response.
+const snapshotBody = 1024
+
+// snapshot summarizes resp for the side-by-side view of a finding, with the
+// headers passed through redact.
+func snapshot(role string, resp *httpx.Response, redact *httpx.Redactor) output.Snapshot {
+    s := output.Snapshot{Role: role, URL: resp.RequestURL, Status: resp.StatusCode, Server: resp.Server, ContentType: resp.ContentType, BodySize: resp.BodySize}
+    for _, h := range redact.Headers(resp.Headers) {
+        s.Headers = append(s.Headers, h.Name+": "+h.Value)
+    }
+    body := resp.Body
+    if len(body) > snapshotBody {
+        body = body[:snapshotBody]
+        // the cut may split the last rune
+        for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(body); i++ { body = body[:len(body)-1] }
+    }
+    if utf8.Valid(body) { s.Body = string(body) }
+    return s
+}
+
+// confidence rates a finding by its signals: a different status code is the strongest
+// hint of another back-end, header-only differences are often noise.
+func confidence(signals map[string]bool) string {
+    n := 0
+    for _, on := range signals {
+        if on { n++ }
+    }
+    switch {
+    case n >= 3 || (signals["status"] && n >= 2):
+        return output.ConfidenceHigh
+    case n >= 2 || signals["status"]:
+        return output.ConfidenceMedium
+    }
+    return output.ConfidenceLow
+}
+
 // Note: target iteration and baseline building happens in the engine for per-URL streaming.
@@ -0,0 +1,207 @@
+package output
+
+import (
+    "fmt"
+    "path"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// AggregateSink merges findings with the same host, path and set of fired signals into
+// one finding that lists every payload in Payloads; the first finding of a group (the
+// stealthiest payload, as modules send them in payload order) is the one passed on.
+//
+// A group is written once no finding joined it for Window (0 = only on Close). With
+// Subtree set, nothing is written before Close: then groups whose paths are Subtree or
+// more siblings under one parent with the same signals collapse into a single finding
+// for the parent, listing the children in Paths.
+type AggregateSink struct {
+    Inner   Sink
+    Window  time.Duration
+    Subtree int
+
+    mu     sync.Mutex
+    groups map[string]*aggGroup
+    order  []string
+    stop   chan struct{}
+
+    closeOnce sync.Once
+    closeErr  error
+}
+
+type aggGroup struct {
+    f    *Finding
+    seen map[string]bool // payloads
+    last time.Time
+}
+
+// NewAggregateSink returns an aggregating wrapper around inner. With a window and no
+// subtree collapsing, idle groups are flushed in the background.
+func NewAggregateSink(inner Sink, window time.Duration, subtree int) *AggregateSink {
+    s := &AggregateSink{Inner: inner, Window: window, Subtree: subtree, groups: make(map[string]*aggGroup), stop: make(chan struct{})}
+    if window > 0 && subtree <= 0 {
+        go s.flusher()
+    }
+    return s
+}
+
+func (s *AggregateSink) Write(f *Finding) error {
+    key := strings.Join([]string{f.Module, f.Host, f.Path, f.Protocol, strings.Join(FindingSignals(f), ",")}, "\x00")
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    g, ok := s.groups[key]
+    if !ok {
+        c := *f
+        c.Payloads = []string{f.Payload}
+        g = &aggGroup{f: &c, seen: map[string]bool{f.Payload: true}}
+        s.groups[key] = g
+        s.order = append(s.order, key)
+    } else if !g.seen[f.Payload] {
+        g.seen[f.Payload] = true
+        g.f.Payloads = append(g.f.Payloads, f.Payload)
+        g.f.Exchanges = append(g.f.Exchanges, f.Exchanges...)
+        g.f.Confidence = maxConfidence(g.f.Confidence, f.Confidence)
+    }
+    g.last = time.Now()
+    return nil
+}
+
+// flusher writes idle groups until Close.
+func (s *AggregateSink) flusher() {
+    t := time.NewTicker(s.Window / 2)
+    defer t.Stop()
+    for {
+        select {
+        case <-s.stop:
+            return
+        case now := <-t.C:
+            s.mu.Lock()
+            s.flushLocked(func(g *aggGroup) bool { return now.Sub(g.last) >= s.Window })
+            s.mu.Unlock()
+        }
+    }
+}
+
+// flushLocked writes and forgets the groups selected by due, in arrival order.
+func (s *AggregateSink) flushLocked(due func(*aggGroup) bool) error {
+    var first error
+    keep := s.order[:0]
+    for _, key := range s.order {
+        g := s.groups[key]
+        if !due(g) {
+            keep = append(keep, key)
+            continue
+        }
+        delete(s.groups, key)
+        if err := s.Inner.Write(g.f); err != nil && first == nil {
+            first = err
+        }
+    }
+    s.order = keep
+    return first
+}
+
+// Close writes all pending groups (collapsing subtrees if enabled) and closes Inner.
+// Later calls return the first result.
+func (s *AggregateSink) Close() error {
+    s.closeOnce.Do(func() { s.closeErr = s.close() })
+    return s.closeErr
+}
+
+func (s *AggregateSink) close() error {
+    close(s.stop)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var err error
+    if s.Subtree > 0 {
+        err = s.collapseLocked()
+    }
+    if ferr := s.flushLocked(func(*aggGroup) bool { return true }); err == nil {
+        err = ferr
+    }
+    if cerr := Close(s.Inner); err == nil {
+        err = cerr
+    }
+    return err
+}
+
+// collapseLocked replaces sibling groups (same module, host, protocol and signals, paths
+// directly under one parent) by one finding for the parent when there are at least
+// Subtree of them.
+func (s *AggregateSink) collapseLocked() error {
+    parents := map[string][]string{}
+    var porder []string
+    for _, key := range s.order {
+        f := s.groups[key].f
+        parent := path.Dir(strings.TrimSuffix(f.Path, "/"))
+        if !strings.HasSuffix(parent, "/") {
+            parent += "/"
+        }
+        pk := strings.Join([]string{f.Module, f.Host, parent, f.Protocol, strings.Join(FindingSignals(f), ",")}, "\x00")
+        if _, ok := parents[pk]; !ok {
+            porder = append(porder, pk)
+        }
+        parents[pk] = append(parents[pk], key)
+    }
+    var first error
+    for _, pk := range porder {
+        keys := parents[pk]
+        if len(keys) < s.Subtree {
+            continue
+        }
+        members := make([]*Finding, len(keys))
+        for i, key := range keys {
+            members[i] = s.groups[key].f
+            delete(s.groups, key)
+        }
+        if err := s.Inner.Write(collapse(members)); err != nil && first == nil {
+            first = err
+        }
+    }
+    keep := s.order[:0]
+    for _, key := range s.order {
+        if _, ok := s.groups[key]; ok {
+            keep = append(keep, key)
+        }
+    }
+    s.order = keep
+    return first
+}
+
+// collapse merges sibling findings into one finding for their parent path.
+func collapse(members []*Finding) *Finding {
+    c := *members[0]
+    c.Path = path.Dir(strings.TrimSuffix(c.Path, "/"))
+    if !strings.HasSuffix(c.Path, "/") {
+        c.Path += "/"
+    }
+    c.Paths, c.Exchanges, c.Responses = nil, nil, nil
+    payloads := map[string]bool{}
+    c.Payloads = nil
+    for _, m := range members {
+        c.Paths = append(c.Paths, m.Path)
+        c.Exchanges = append(c.Exchanges, m.Exchanges...)
+        c.Confidence = maxConfidence(c.Confidence, m.Confidence)
+        for _, p := range m.Payloads {
+            if !payloads[p] {
+                payloads[p] = true
+                c.Payloads = append(c.Payloads, p)
+            }
+        }
+    }
+    sort.Strings(c.Paths)
+    c.Notes = append([]string{fmt.Sprintf("%d sibling paths under %s show the same signals: the whole subtree is likely proxied to another back-end", len(members), c.Path)}, c.Notes...)
+    c.Fingerprint = Fingerprint(&c)
+    return &c
+}
+
+var confidenceRank = map[string]int{ConfidenceLow: 1, ConfidenceMedium: 2, ConfidenceHigh: 3}
+
+func maxConfidence(a, b string) string {
+    if confidenceRank[b] > confidenceRank[a] {
+        return b
+    }
+    return a
+}
@@ -0,0 +1,141 @@
+package output
+
+import (
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+)
+
+// memSink collects written findings.
+type memSink struct {
+    mu     sync.Mutex
+    got    []*Finding
+    closed bool
+}
+
+func (m *memSink) Write(f *Finding) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.got = append(m.got, f)
+    return nil
+}
+
+func (m *memSink) Close() error {
+    m.closed = true
+    return nil
+}
+
+func (m *memSink) findings() []*Finding {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return append([]*Finding(nil), m.got...)
+}
+
+func aggFinding(path, payload, confidence string, exchange uint64, signals ...string) *Finding {
+    f := &Finding{Module: "scpt", Host: "https://a.example", Path: path, Payload: payload, Confidence: confidence, Exchanges: []uint64{exchange}, Signals: map[string]bool{}}
+    for _, s := range signals {
+        f.Signals[s] = true
+    }
+    return f
+}
+
+func TestAggregateSinkMergesPayloads(t *testing.T) {
+    inner := &memSink{}
+    s := NewAggregateSink(inner, 0, 0)
+    for _, f := range []*Finding{
+        aggFinding("/api/", "..;/", ConfidenceLow, 1, "status"),
+        aggFinding("/api/", "%2e%2e/", ConfidenceHigh, 2, "status"),
+        aggFinding("/api/", "..;/", ConfidenceLow, 3, "status"), // repeated payload
+        aggFinding("/api/", "..;/", ConfidenceLow, 4, "status", "headers"),
+        aggFinding("/app/", "..;/", ConfidenceMedium, 5, "status"),
+    } {
+        if err := s.Write(f); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if n := len(inner.findings()); n != 0 {
+        t.Fatalf("%d findings written before Close without a window", n)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatalf("second Close: %v", err)
+    }
+    got := inner.findings()
+    if len(got) != 3 || !inner.closed {
+        t.Fatalf("%d findings after Close (inner closed %v), want 3", len(got), inner.closed)
+    }
+    first := got[0]
+    if first.Payload != "..;/" || !reflect.DeepEqual(first.Payloads, []string{"..;/", "%2e%2e/"}) {
+        t.Errorf("payload %q, payloads %v", first.Payload, first.Payloads)
+    }
+    if !reflect.DeepEqual(first.Exchanges, []uint64{1, 2}) || first.Confidence != ConfidenceHigh {
+        t.Errorf("exchanges %v, confidence %q", first.Exchanges, first.Confidence)
+    }
+    if got[1].Path != "/api/" || len(got[1].Payloads) != 1 || got[2].Path != "/app/" {
+        t.Errorf("other groups: %+v %+v", got[1], got[2])
+    }
+}
+
+func TestAggregateSinkWindow(t *testing.T) {
+    inner := &memSink{}
+    s := NewAggregateSink(inner, 40*time.Millisecond, 0)
+    s.Write(aggFinding("/api/", "..;/", ConfidenceLow, 1, "status"))
+    deadline := time.Now().Add(2 * time.Second)
+    for len(inner.findings()) == 0 && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if n := len(inner.findings()); n != 1 {
+        t.Fatalf("%d findings after the window, want the idle group", n)
+    }
+    s.Write(aggFinding("/api/", "%2e%2e/", ConfidenceLow, 2, "status"))
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if n := len(inner.findings()); n != 2 {
+        t.Errorf("%d findings after Close, want 2 (a flushed group starts over)", n)
+    }
+}
+
+func TestAggregateSinkCollapsesSubtrees(t *testing.T) {
+    inner := &memSink{}
+    s := NewAggregateSink(inner, time.Millisecond, 3)
+    for _, f := range []*Finding{
+        aggFinding("/api/users/", "..;/", ConfidenceLow, 1, "status"),
+        aggFinding("/api/orders/", "..;/", ConfidenceMedium, 2, "status"),
+        aggFinding("/api/items/", "%2e%2e/", ConfidenceLow, 3, "status"),
+        aggFinding("/api/items/", "..;/", ConfidenceLow, 4, "status"),
+        aggFinding("/api/other/", "..;/", ConfidenceLow, 5, "status", "headers"), // other signals
+        aggFinding("/web/a/", "..;/", ConfidenceLow, 6, "status"),                // too few siblings
+        aggFinding("/web/b/", "..;/", ConfidenceLow, 7, "status"),
+    } {
+        s.Write(f)
+    }
+    time.Sleep(20 * time.Millisecond)
+    if n := len(inner.findings()); n != 0 {
+        t.Fatalf("%d findings written before Close with subtree collapsing", n)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    got := inner.findings()
+    var paths []string
+    for _, f := range got {
+        paths = append(paths, f.Path)
+    }
+    if want := []string{"/api/", "/api/other/", "/web/a/", "/web/b/"}; !reflect.DeepEqual(paths, want) {
+        t.Fatalf("paths = %v, want %v", paths, want)
+    }
+    c := got[0]
+    if !reflect.DeepEqual(c.Paths, []string{"/api/items/", "/api/orders/", "/api/users/"}) {
+        t.Errorf("collapsed Paths = %v", c.Paths)
+    }
+    if !reflect.DeepEqual(c.Payloads, []string{"..;/", "%2e%2e/"}) || !reflect.DeepEqual(c.Exchanges, []uint64{1, 2, 3, 4}) {
+        t.Errorf("collapsed payloads %v, exchanges %v", c.Payloads, c.Exchanges)
+    }
+    if c.Confidence != ConfidenceMedium || c.Fingerprint != Fingerprint(c) || len(c.Notes) == 0 {
+        t.Errorf("collapsed confidence %q, fingerprint %q, notes %v", c.Confidence, c.Fingerprint, c.Notes)
+    }
+}
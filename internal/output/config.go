@@ -0,0 +1,217 @@
+package output
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+)
+
+// Sink types for SinkConfig.Type.
+const (
+    SinkStdout  = "stdout"
+    SinkJSONL   = "jsonl"
+    SinkSARIF   = "sarif"
+    SinkHTML    = "html"
+    SinkSQLite  = "sqlite"
+    SinkWebhook = "webhook"
+)
+
+// Filter selects the findings a sink receives. Empty fields match everything; list
+// fields match when any entry does.
+type Filter struct {
+    MinConfidence string   `json:"min_confidence,omitempty"` // findings without confidence count as medium
+    Modules       []string `json:"modules,omitempty"`
+    Signals       []string `json:"signals,omitempty"` // at least one of these fired
+    Hosts         []string `json:"hosts,omitempty"`   // substrings of the finding's host
+    Statuses      []int    `json:"statuses,omitempty"`
+}
+
+// Empty tells whether the filter lets every finding through.
+func (fl Filter) Empty() bool {
+    return fl.MinConfidence == "" && len(fl.Modules) == 0 && len(fl.Signals) == 0 && len(fl.Hosts) == 0 && len(fl.Statuses) == 0
+}
+
+// Match tells whether f passes the filter.
+func (fl Filter) Match(f *Finding) bool {
+    if fl.MinConfidence != "" {
+        level := f.Confidence
+        if level == "" {
+            level = ConfidenceMedium
+        }
+        if confidenceRank[level] < confidenceRank[fl.MinConfidence] {
+            return false
+        }
+    }
+    if len(fl.Modules) > 0 && !anyOf(fl.Modules, func(m string) bool { return m == f.Module }) {
+        return false
+    }
+    if len(fl.Signals) > 0 && !anyOf(fl.Signals, func(s string) bool { return f.Signals[s] }) {
+        return false
+    }
+    if len(fl.Hosts) > 0 && !anyOf(fl.Hosts, func(h string) bool { return strings.Contains(f.Host, h) }) {
+        return false
+    }
+    if len(fl.Statuses) > 0 {
+        for _, st := range fl.Statuses {
+            if st == f.Status {
+                return true
+            }
+        }
+        return false
+    }
+    return true
+}
+
+func anyOf(list []string, fn func(string) bool) bool {
+    for _, v := range list {
+        if fn(v) {
+            return true
+        }
+    }
+    return false
+}
+
+// FilteredSink passes only the findings matching Filter to Inner.
+type FilteredSink struct {
+    Inner  Sink
+    Filter Filter
+}
+
+func (s FilteredSink) Write(f *Finding) error {
+    if !s.Filter.Match(f) {
+        return nil
+    }
+    return s.Inner.Write(f)
+}
+
+func (s FilteredSink) Close() error { return Close(s.Inner) }
+
+// SinkConfig declares one sink of a sinks file. Path is the output directory for jsonl
+// (the file with Single) and the file for sarif, html and sqlite; Single and Gzip are the
+// JSONLConfig options, the webhook fields mirror WebhookConfig (wait in seconds, rate in
+// requests per minute).
+type SinkConfig struct {
+    Type   string `json:"type"`
+    Path   string `json:"path,omitempty"`
+    Filter Filter `json:"filter,omitempty"`
+
+    Single bool `json:"single,omitempty"`
+    Gzip   bool `json:"gzip,omitempty"`
+
+    URL      string `json:"url,omitempty"`
+    Preset   string `json:"preset,omitempty"`
+    Template string `json:"template,omitempty"`
+    Batch    int    `json:"batch,omitempty"`
+    Wait     int    `json:"wait,omitempty"`
+    Rate     int    `json:"rate,omitempty"`
+    Retries  int    `json:"retries,omitempty"`
+}
+
+// SinksConfig is the sinks file given with --sinks:
+//
+//    {"sinks": [
+//        {"type": "stdout", "filter": {"min_confidence": "high"}},
+//        {"type": "jsonl", "path": "out/"},
+//        {"type": "jsonl", "path": "all.jsonl.gz", "single": true, "gzip": true},
+//        {"type": "sqlite", "path": "results.db"},
+//        {"type": "webhook", "url": "https://hooks.slack.com/...", "preset": "slack"}
+//    ]}
+type SinksConfig struct {
+    Sinks []SinkConfig `json:"sinks"`
+}
+
+// LoadSinksConfig reads and checks a sinks file; unknown fields are errors so typos do
+// not silently drop a filter.
+func LoadSinksConfig(name string) (*SinksConfig, error) {
+    fp, err := os.Open(name)
+    if err != nil {
+        return nil, err
+    }
+    defer fp.Close()
+    dec := json.NewDecoder(fp)
+    dec.DisallowUnknownFields()
+    var cfg SinksConfig
+    if err := dec.Decode(&cfg); err != nil {
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    for i, sc := range cfg.Sinks {
+        if err := sc.check(); err != nil {
+            return nil, fmt.Errorf("%s: sink %d (%s): %w", name, i+1, sc.Type, err)
+        }
+    }
+    return &cfg, nil
+}
+
+func (sc SinkConfig) check() error {
+    if c := sc.Filter.MinConfidence; c != "" && confidenceRank[c] == 0 {
+        return fmt.Errorf("invalid min_confidence %q (want %s, %s or %s)", c, ConfidenceLow, ConfidenceMedium, ConfidenceHigh)
+    }
+    switch sc.Type {
+    case SinkStdout:
+    case SinkJSONL, SinkSARIF, SinkHTML, SinkSQLite:
+        if sc.Path == "" {
+            return fmt.Errorf("path is required")
+        }
+    case SinkWebhook:
+        if sc.URL == "" {
+            return fmt.Errorf("url is required")
+        }
+    default:
+        return fmt.Errorf("unknown sink type (want %s, %s, %s, %s, %s or %s)", SinkStdout, SinkJSONL, SinkSARIF, SinkHTML, SinkSQLite, SinkWebhook)
+    }
+    return nil
+}
+
+// Build creates the sink, wrapped in its filter. label names the scan in a SQLite store.
+func (sc SinkConfig) Build(label string) (Sink, error) {
+    if err := sc.check(); err != nil {
+        return nil, err
+    }
+    var s Sink
+    var err error
+    switch sc.Type {
+    case SinkStdout:
+        s = StdoutSink{}
+    case SinkJSONL:
+        s, err = NewJSONLSink(JSONLConfig{Path: sc.Path, Single: sc.Single, Gzip: sc.Gzip})
+    case SinkSARIF:
+        s, err = NewSARIFSink(sc.Path, "1.0.0")
+    case SinkHTML:
+        s = &HTMLSink{Path: sc.Path}
+    case SinkSQLite:
+        s, err = NewSQLiteSink(sc.Path, label)
+    case SinkWebhook:
+        // the webhook's own threshold follows the filter, so the filter alone decides
+        min := sc.Filter.MinConfidence
+        if min == "" {
+            min = ConfidenceLow
+        }
+        s, err = NewWebhookSink(WebhookConfig{
+            URL:           sc.URL,
+            Preset:        sc.Preset,
+            Template:      sc.Template,
+            MinConfidence: min,
+            BatchSize:     sc.Batch,
+            BatchWait:     time.Duration(sc.Wait) * time.Second,
+            Interval:      ratePerMinute(sc.Rate),
+            Retries:       sc.Retries,
+        })
+    }
+    if err != nil {
+        return nil, err
+    }
+    if sc.Filter.Empty() {
+        return s, nil
+    }
+    return FilteredSink{Inner: s, Filter: sc.Filter}, nil
+}
+
+// ratePerMinute turns a request rate into the interval between requests (0 = default).
+func ratePerMinute(n int) time.Duration {
+    if n <= 0 {
+        return 0
+    }
+    return time.Minute / time.Duration(n)
+}
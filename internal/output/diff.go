@@ -0,0 +1,183 @@
+package output
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "net/url"
+    "path"
+    "sort"
+    "strings"
+
+    "pohek/internal/payload"
+)
+
+// Changes reported by DiffFindings.
+const (
+    DiffNew      = "new"
+    DiffResolved = "resolved"
+    DiffChanged  = "changed"
+)
+
+// Fingerprint identifies a finding across scans: module, host, normalized path, payload
+// family and the signals that fired (plus the protocol when one was forced, see
+// Finding.ProtocolForced; a negotiated protocol may differ between runs). Payloads of one
+// family and changes in status text or timing do not affect it.
+func Fingerprint(f *Finding) string {
+    sum := sha256.Sum256([]byte(strings.Join(append(findingLocation(f), strings.Join(FindingSignals(f), ",")), "\x00")))
+    return hex.EncodeToString(sum[:12])
+}
+
+// findingLocation is the fingerprint without the signals: where and how the traversal
+// was found.
+func findingLocation(f *Finding) []string {
+    return []string{f.Module, normalizeHost(f.Host), normalizePath(f.Path), payload.Family(f.Payload), forcedProtocol(f)}
+}
+
+// forcedProtocol is f.Protocol when the user forced it, "" when it was negotiated.
+func forcedProtocol(f *Finding) string {
+    if !f.ProtocolForced {
+        return ""
+    }
+    return f.Protocol
+}
+
+// normalizeHost lower-cases the origin and drops a default port.
+func normalizeHost(h string) string {
+    u, err := url.Parse(strings.ToLower(strings.TrimSpace(h)))
+    if err != nil || u.Host == "" {
+        return strings.ToLower(h)
+    }
+    if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+        u.Host = u.Hostname()
+    }
+    return u.Scheme + "://" + u.Host
+}
+
+// normalizePath drops query and fragment, collapses repeated slashes and always ends in
+// "/", the way the scpt module treats paths.
+func normalizePath(p string) string {
+    if i := strings.IndexAny(p, "?#"); i >= 0 {
+        p = p[:i]
+    }
+    for strings.Contains(p, "//") {
+        p = strings.ReplaceAll(p, "//", "/")
+    }
+    p = path.Join("/", p)
+    if p != "/" {
+        p += "/"
+    }
+    return p
+}
+
+// DiffEntry is one difference between two result sets. Previous is set for resolved and
+// changed findings; Changes lists what differs for changed ones.
+type DiffEntry struct {
+    Change      string   `json:"change"`
+    Fingerprint string   `json:"fingerprint"`
+    Finding     *Finding `json:"finding,omitempty"`
+    Previous    *Finding `json:"previous,omitempty"`
+    Changes     []string `json:"changes,omitempty"`
+}
+
+// DiffFindings compares an old and a new result set by fingerprint. A fingerprint only
+// in the new set is new unless the old set has a finding at the same location (same
+// fingerprint without signals), which makes it changed; leftovers of the old set are
+// resolved. Findings present in both are changed when status, server, content type or
+// confidence differ. Entries are sorted by host, path and payload.
+func DiffFindings(old, cur []*Finding) []DiffEntry {
+    oldBy, curBy := groupByFingerprint(old), groupByFingerprint(cur)
+    // old findings that disappeared, by location, to pair them with signal changes
+    gone := map[string][]string{}
+    for fp, f := range oldBy {
+        if _, ok := curBy[fp]; !ok {
+            loc := strings.Join(findingLocation(f), "\x00")
+            gone[loc] = append(gone[loc], fp)
+        }
+    }
+    for _, fps := range gone {
+        sort.Strings(fps)
+    }
+
+    var out []DiffEntry
+    for fp, f := range curBy {
+        if prev, ok := oldBy[fp]; ok {
+            if ch := findingChanges(prev, f); len(ch) > 0 {
+                out = append(out, DiffEntry{Change: DiffChanged, Fingerprint: fp, Finding: f, Previous: prev, Changes: ch})
+            }
+            continue
+        }
+        loc := strings.Join(findingLocation(f), "\x00")
+        if fps := gone[loc]; len(fps) > 0 {
+            prev := oldBy[fps[0]]
+            gone[loc] = fps[1:]
+            delete(oldBy, fps[0])
+            out = append(out, DiffEntry{Change: DiffChanged, Fingerprint: fp, Finding: f, Previous: prev, Changes: findingChanges(prev, f)})
+            continue
+        }
+        out = append(out, DiffEntry{Change: DiffNew, Fingerprint: fp, Finding: f})
+    }
+    for _, fps := range gone {
+        for _, fp := range fps {
+            out = append(out, DiffEntry{Change: DiffResolved, Fingerprint: fp, Previous: oldBy[fp]})
+        }
+    }
+    sort.Slice(out, func(i, j int) bool {
+        a, b := out[i].subject(), out[j].subject()
+        if a.Host != b.Host {
+            return a.Host < b.Host
+        }
+        if a.Path != b.Path {
+            return a.Path < b.Path
+        }
+        if a.Payload != b.Payload {
+            return a.Payload < b.Payload
+        }
+        return out[i].Change < out[j].Change
+    })
+    return out
+}
+
+// subject is the finding an entry is about: the current one, or the old one if resolved.
+func (e DiffEntry) subject() *Finding {
+    if e.Finding != nil {
+        return e.Finding
+    }
+    return e.Previous
+}
+
+// groupByFingerprint keeps one finding per fingerprint, the one with the smallest
+// payload, so that the choice does not depend on scan order. Fingerprints are recomputed
+// rather than read from Finding.Fingerprint, so result sets written by older versions
+// compare by the current definition.
+func groupByFingerprint(fs []*Finding) map[string]*Finding {
+    out := make(map[string]*Finding, len(fs))
+    for _, f := range fs {
+        fp := Fingerprint(f)
+        if cur, ok := out[fp]; !ok || f.Payload < cur.Payload {
+            out[fp] = f
+        }
+    }
+    return out
+}
+
+// findingChanges describes how cur differs from prev, e.g. "status 404 -> 200".
+func findingChanges(prev, cur *Finding) []string {
+    var ch []string
+    if a, b := strings.Join(FindingSignals(prev), ","), strings.Join(FindingSignals(cur), ","); a != b {
+        ch = append(ch, fmt.Sprintf("signals %s -> %s", a, b))
+    }
+    if prev.Status != cur.Status {
+        ch = append(ch, fmt.Sprintf("status %d -> %d", prev.Status, cur.Status))
+    }
+    for _, c := range [][3]string{
+        {"server", prev.Server, cur.Server},
+        {"content type", prev.ContentType, cur.ContentType},
+        {"confidence", prev.Confidence, cur.Confidence},
+    } {
+        if c[1] != c[2] {
+            ch = append(ch, fmt.Sprintf("%s %q -> %q", c[0], c[1], c[2]))
+        }
+    }
+    return ch
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestFingerprint(t *testing.T) {
+    base := Finding{Module: "scpt", Host: "https://a.example", Path: "/api/", Payload: "..;/", Protocol: "HTTP/1.1", Signals: map[string]bool{"status": true}}
+    with := func(edit func(f *Finding)) *Finding {
+        f := base
+        f.Signals = map[string]bool{"status": true}
+        edit(&f)
+        return &f
+    }
+    want := Fingerprint(&base)
+    same := map[string]*Finding{
+        "host case and default port": with(func(f *Finding) { f.Host = "HTTPS://A.example:443" }),
+        "path without slash":         with(func(f *Finding) { f.Path = "/api" }),
+        "path query and slashes":     with(func(f *Finding) { f.Path = "//api?x=1" }),
+        "encoded payload":            with(func(f *Finding) { f.Payload = "%2e%2e;/" }),
+        "status and timing":          with(func(f *Finding) { f.Status = 500; f.Server = "x" }),
+        "negotiated protocol":        with(func(f *Finding) { f.Protocol = "HTTP/2.0" }),
+        "unset signal":               with(func(f *Finding) { f.Signals["server"] = false }),
+    }
+    for name, f := range same {
+        if got := Fingerprint(f); got != want {
+            t.Errorf("%s: fingerprint changed", name)
+        }
+    }
+    differ := map[string]*Finding{
+        "module":          with(func(f *Finding) { f.Module = "other" }),
+        "host":            with(func(f *Finding) { f.Host = "https://b.example" }),
+        "port":            with(func(f *Finding) { f.Host = "https://a.example:8443" }),
+        "path":            with(func(f *Finding) { f.Path = "/app/" }),
+        "payload family":  with(func(f *Finding) { f.Payload = "../" }),
+        "signals":         with(func(f *Finding) { f.Signals["headers"] = true }),
+        "forced protocol": with(func(f *Finding) { f.ProtocolForced = true }),
+    }
+    for name, f := range differ {
+        if got := Fingerprint(f); got == want {
+            t.Errorf("%s: fingerprint did not change", name)
+        }
+    }
+    h2 := with(func(f *Finding) { f.Protocol, f.ProtocolForced = "HTTP/2.0", true })
+    h1 := with(func(f *Finding) { f.ProtocolForced = true })
+    if Fingerprint(h1) == Fingerprint(h2) {
+        t.Error("forced protocols do not tell findings apart")
+    }
+}
+
+func TestDiffFindings(t *testing.T) {
+    f := func(path, payload string, status int, signals ...string) *Finding {
+        fd := &Finding{Module: "scpt", Host: "https://a.example", Path: path, Payload: payload, Status: status, Signals: map[string]bool{}}
+        for _, s := range signals {
+            fd.Signals[s] = true
+        }
+        return fd
+    }
+    old := []*Finding{
+        f("/same/", "..;/", 200, "status"),
+        f("/status/", "..;/", 404, "status"),
+        f("/signals/", "..;/", 200, "status"),
+        f("/gone/", "..;/", 200, "status"),
+        f("/dup/", "%2e%2e;/", 200, "status"),
+    }
+    cur := []*Finding{
+        f("/same", "..;/", 200, "status"), // path normalized
+        f("/status/", "..;/", 200, "status"),
+        f("/signals/", "..;/", 200, "status", "headers"),
+        f("/new/", "..;/", 200, "status"),
+        f("/dup/", "..;/", 200, "status"),
+        f("/dup/", "%2E%2E;/", 200, "status"), // same family twice
+    }
+    type row struct {
+        change, path string
+        changes      []string
+    }
+    var got []row
+    for _, e := range DiffFindings(old, cur) {
+        got = append(got, row{e.Change, e.subject().Path, e.Changes})
+    }
+    want := []row{
+        {DiffResolved, "/gone/", nil},
+        {DiffNew, "/new/", nil},
+        {DiffChanged, "/signals/", []string{"signals status -> headers,status"}},
+        {DiffChanged, "/status/", []string{"status 404 -> 200"}},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("DiffFindings =\n%+v\nwant\n%+v", got, want)
+    }
+}
@@ -0,0 +1,39 @@
+package output
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "os"
+    "path/filepath"
+)
+
+// Evidence references the stored request and response of one exchange behind a finding.
+type Evidence struct {
+    Role     string `json:"role"`     // "traversal", "parent", "nonexistent"
+    Request  string `json:"request"`  // file with the raw request
+    Response string `json:"response"` // file with the raw response (decoded body)
+}
+
+// EvidenceStore keeps raw requests and responses in a directory under content-addressed
+// names (sha256 of the content), so a baseline shared by many findings is stored once.
+type EvidenceStore struct {
+    Dir string
+}
+
+// NewEvidenceStore creates the evidence directory.
+func NewEvidenceStore(dir string) (*EvidenceStore, error) {
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return nil, err
+    }
+    return &EvidenceStore{Dir: dir}, nil
+}
+
+// Put stores data as <sha256><ext> unless it exists already and returns its path.
+func (s *EvidenceStore) Put(data []byte, ext string) (string, error) {
+    sum := sha256.Sum256(data)
+    name := filepath.Join(s.Dir, hex.EncodeToString(sum[:])+ext)
+    if _, err := os.Stat(name); err == nil {
+        return name, nil
+    }
+    return name, writeAtomic(name, data)
+}
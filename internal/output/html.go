@@ -0,0 +1,210 @@
+package output
+
+import (
+    "bytes"
+    "html/template"
+    "sort"
+    "strings"
+    "time"
+)
+
+// HTMLSink collects findings and renders them as a self-contained HTML report on Close.
+type HTMLSink struct {
+    Path     string
+    findings []*Finding
+}
+
+func (s *HTMLSink) Write(f *Finding) error {
+    s.findings = append(s.findings, f)
+    return nil
+}
+
+func (s *HTMLSink) Close() error { return WriteHTMLReport(s.Path, s.findings) }
+
+// WriteHTMLReport renders findings into an offline HTML file (no external assets),
+// grouped by host and path, with client-side filters by module, signal and confidence.
+func WriteHTMLReport(name string, findings []*Finding) error {
+    var buf bytes.Buffer
+    if err := reportTemplate.Execute(&buf, newReport(findings)); err != nil {
+        return err
+    }
+    return writeAtomic(name, buf.Bytes())
+}
+
+type report struct {
+    Generated   time.Time
+    Total       int
+    Hosts       []reportHost
+    Modules     []string
+    Signals     []string
+    Confidences []string
+}
+
+type reportHost struct {
+    Host  string
+    Count int
+    Paths []reportPath
+}
+
+type reportPath struct {
+    Path     string
+    Findings []reportFinding
+}
+
+type reportFinding struct {
+    *Finding
+    Fired []string // signals that fired, sorted
+}
+
+// Confidence for filtering; findings without one are treated as medium.
+func (f reportFinding) Level() string {
+    if f.Confidence == "" {
+        return ConfidenceMedium
+    }
+    return f.Confidence
+}
+
+func newReport(findings []*Finding) report {
+    r := report{Generated: time.Now(), Total: len(findings), Confidences: []string{ConfidenceHigh, ConfidenceMedium, ConfidenceLow}}
+    modules, signals := map[string]bool{}, map[string]bool{}
+    hosts := map[string]map[string][]reportFinding{}
+    for _, f := range findings {
+        rf := reportFinding{Finding: f, Fired: FindingSignals(f)}
+        for _, name := range rf.Fired {
+            signals[name] = true
+        }
+        modules[f.Module] = true
+        if hosts[f.Host] == nil {
+            hosts[f.Host] = map[string][]reportFinding{}
+        }
+        hosts[f.Host][f.Path] = append(hosts[f.Host][f.Path], rf)
+    }
+    r.Modules, r.Signals = sortedKeys(modules), sortedKeys(signals)
+    for _, host := range sortedKeys(hosts) {
+        h := reportHost{Host: host}
+        for _, path := range sortedKeys(hosts[host]) {
+            fs := hosts[host][path]
+            sort.SliceStable(fs, func(i, j int) bool { return fs[i].Payload < fs[j].Payload })
+            h.Paths = append(h.Paths, reportPath{Path: path, Findings: fs})
+            h.Count += len(fs)
+        }
+        r.Hosts = append(r.Hosts, h)
+    }
+    return r
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+    "join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>pohek report</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:0;color:#222;background:#f6f6f6}
+header{background:#263238;color:#fff;padding:12px 20px}
+header h1{margin:0;font-size:20px}
+#filters{background:#fff;border-bottom:1px solid #ddd;padding:8px 20px;position:sticky;top:0;z-index:1}
+#filters fieldset{display:inline-block;border:1px solid #ccc;margin:0 8px 4px 0;padding:2px 8px}
+main{padding:0 20px 40px}
+section.host{background:#fff;margin:16px 0;padding:8px 16px;border:1px solid #ddd}
+h2{font-size:17px;margin:6px 0}
+h3{font-size:15px;margin:10px 0 4px;font-family:monospace}
+details.finding{border-left:4px solid #999;margin:4px 0;padding:2px 8px;background:#fafafa}
+details.finding.high{border-color:#c62828}
+details.finding.medium{border-color:#ef6c00}
+details.finding.low{border-color:#9e9e9e}
+summary{cursor:pointer;font-family:monospace}
+.badge{display:inline-block;padding:0 6px;border-radius:3px;background:#eee;font-size:12px;margin-right:4px}
+.cols{display:grid;grid-template-columns:repeat(auto-fit,minmax(280px,1fr));gap:8px;margin:8px 0}
+.col{background:#fff;border:1px solid #ddd;padding:6px;overflow:auto}
+.col h4{margin:0 0 4px;font-size:13px;text-transform:uppercase}
+.col.traversal{border-color:#c62828}
+pre{white-space:pre-wrap;word-break:break-all;font-size:12px;background:#f0f0f0;padding:6px;margin:4px 0;max-height:320px;overflow:auto}
+button.copy{font-size:12px}
+.hidden{display:none}
+</style>
+</head>
+<body>
+<header><h1>pohek report</h1><div>{{.Total}} findings on {{len .Hosts}} hosts, generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</div></header>
+<div id="filters">
+<fieldset><legend>module</legend>{{range .Modules}}<label><input type="checkbox" data-filter="module" value="{{.}}" checked> {{.}}</label> {{end}}</fieldset>
+<fieldset><legend>signal</legend>{{range .Signals}}<label><input type="checkbox" data-filter="signal" value="{{.}}" checked> {{.}}</label> {{end}}</fieldset>
+<fieldset><legend>confidence</legend>{{range .Confidences}}<label><input type="checkbox" data-filter="confidence" value="{{.}}" checked> {{.}}</label> {{end}}</fieldset>
+<span id="shown"></span>
+</div>
+<main>
+{{range .Hosts}}<section class="host">
+<h2>{{.Host}} <span class="badge">{{.Count}}</span></h2>
+{{range .Paths}}<div class="path">
+<h3>{{.Path}}</h3>
+{{range .Findings}}<details class="finding {{.Level}}" data-module="{{.Module}}" data-signals="{{join .Fired " "}}" data-confidence="{{.Level}}">
+<summary><span class="badge">{{.Level}}</span><span class="badge">{{.Module}}</span>{{.Payload}} &rarr; {{.Status}}{{if .Protocol}} <span class="badge">{{.Protocol}}</span>{{end}} [{{join .Fired ", "}}]</summary>
+<p><a href="{{.URL}}">{{.URL}}</a> &middot; {{.Timestamp.Format "2006-01-02 15:04:05"}}</p>
+{{if gt (len .Payloads) 1}}<p>Payloads: {{range .Payloads}}<code>{{.}}</code> {{end}}</p>{{end}}
+{{if .Paths}}<p>Paths: {{range .Paths}}<code>{{.}}</code> {{end}}</p>{{end}}
+{{if .Notes}}<ul>{{range .Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if or .HeadersAdded .HeadersRemoved}}<p>Headers: {{join .HeadersAdded " "}} {{join .HeadersRemoved " "}}</p>{{end}}
+{{if .BodyDiff}}<p>Body: {{.BodyDiff}}</p>{{end}}
+{{if .Evidence}}<p>Evidence: {{range .Evidence}}{{.Role}} (<a href="{{.Request}}">request</a>, <a href="{{.Response}}">response</a>) {{end}}</p>{{end}}
+{{if .Responses}}<div class="cols">{{range .Responses}}<div class="col {{.Role}}">
+<h4>{{.Role}}</h4>
+<div><code>{{.URL}}</code></div>
+<div>status <b>{{.Status}}</b>{{if .Server}} &middot; server {{.Server}}{{end}}{{if .ContentType}} &middot; {{.ContentType}}{{end}} &middot; {{.BodySize}} bytes</div>
+{{if .Headers}}<pre>{{join .Headers "\n"}}</pre>{{end}}
+{{if .Body}}<pre>{{.Body}}</pre>{{end}}
+</div>{{end}}</div>{{end}}
+{{if .Request}}<div>Reproduction request <button class="copy">copy</button><pre class="repro">{{.Request}}</pre></div>{{end}}
+{{if .Curl}}<div>curl <button class="copy">copy</button><pre class="repro">{{.Curl}}</pre></div>{{end}}
+{{if .Python}}<div>Python <button class="copy">copy</button><pre class="repro">{{.Python}}</pre></div>{{end}}
+</details>
+{{end}}</div>
+{{end}}</section>
+{{end}}
+</main>
+<script>
+(function(){
+  var boxes = document.querySelectorAll('#filters input');
+  function checked(kind){
+    var s = {};
+    document.querySelectorAll('#filters input[data-filter="'+kind+'"]').forEach(function(b){ if(b.checked) s[b.value] = true; });
+    return s;
+  }
+  function apply(){
+    var mods = checked('module'), sigs = checked('signal'), confs = checked('confidence'), shown = 0;
+    document.querySelectorAll('details.finding').forEach(function(d){
+      var sig = d.dataset.signals === '' || d.dataset.signals.split(' ').some(function(s){ return sigs[s]; });
+      var ok = mods[d.dataset.module] && confs[d.dataset.confidence] && sig;
+      d.classList.toggle('hidden', !ok);
+      if(ok) shown++;
+    });
+    document.querySelectorAll('div.path, section.host').forEach(function(g){
+      g.classList.toggle('hidden', !g.querySelector('details.finding:not(.hidden)'));
+    });
+    document.getElementById('shown').textContent = shown + ' shown';
+  }
+  boxes.forEach(function(b){ b.addEventListener('change', apply); });
+  document.querySelectorAll('button.copy').forEach(function(b){
+    b.addEventListener('click', function(){
+      var pre = b.nextElementSibling;
+      if(navigator.clipboard && window.isSecureContext){ navigator.clipboard.writeText(pre.textContent); return; }
+      var r = document.createRange(); r.selectNodeContents(pre);
+      var sel = window.getSelection(); sel.removeAllRanges(); sel.addRange(r);
+      document.execCommand('copy'); sel.removeAllRanges();
+    });
+  });
+  apply();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,214 @@
+package output
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+// JSONLConfig configures a JSONLSink. Zero values get the defaults noted per field.
+type JSONLConfig struct {
+    Path          string        // output directory, or the file in single-file mode
+    Single        bool          // write every host to Path instead of one file per host
+    Gzip          bool          // gzip the output; per-host files are named host.jsonl.gz
+    MaxOpen       int           // per-host files kept open (default 64; the least recently used is closed)
+    FlushInterval time.Duration // how often buffered findings reach the files (default 1s)
+}
+
+// JSONLSink writes findings as JSON lines, by default to one file per host inside a
+// directory. Files stay open and buffered between findings: a background goroutine
+// flushes them every FlushInterval, and Close flushes, fsyncs and closes them. Files are
+// appended to, so a reopened gzip file holds one gzip member per session; gzip readers,
+// LoadFindings among them, read them as one stream.
+type JSONLSink struct {
+    cfg JSONLConfig
+
+    mu    sync.Mutex
+    files map[string]*jsonlFile // by file name
+    tick  uint64                // use counter for closing the least recently used file
+    stop  chan struct{}
+    done  chan struct{}
+
+    closeOnce sync.Once
+    closeErr  error
+}
+
+type jsonlFile struct {
+    fp    *os.File
+    gz    *gzip.Writer // nil without Gzip
+    buf   *bufio.Writer
+    used  uint64
+    dirty bool
+}
+
+// NewJSONLSink creates the output directory (or the single file's directory) and starts
+// the flusher.
+func NewJSONLSink(cfg JSONLConfig) (*JSONLSink, error) {
+    if cfg.Path == "" {
+        return nil, fmt.Errorf("jsonl sink: no output path")
+    }
+    if cfg.MaxOpen <= 0 {
+        cfg.MaxOpen = 64
+    }
+    if cfg.FlushInterval <= 0 {
+        cfg.FlushInterval = time.Second
+    }
+    dir := cfg.Path
+    if cfg.Single {
+        dir = filepath.Dir(cfg.Path)
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return nil, err
+    }
+    s := &JSONLSink{cfg: cfg, files: make(map[string]*jsonlFile), stop: make(chan struct{}), done: make(chan struct{})}
+    go s.flusher()
+    return s, nil
+}
+
+func (s *JSONLSink) Write(f *Finding) error {
+    b, err := json.Marshal(f)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    jf, err := s.fileLocked(s.fileName(f.Host))
+    if err != nil {
+        return err
+    }
+    jf.dirty = true
+    _, err = jf.buf.Write(append(b, '\n'))
+    return err
+}
+
+// fileName returns the file a finding for host goes to.
+func (s *JSONLSink) fileName(host string) string {
+    if s.cfg.Single {
+        return s.cfg.Path
+    }
+    name := safeFilename(host) + ".jsonl"
+    if s.cfg.Gzip {
+        name += ".gz"
+    }
+    return filepath.Join(s.cfg.Path, name)
+}
+
+// fileLocked returns the open file for name, opening it (and closing the least recently
+// used one when MaxOpen files are open) if needed.
+func (s *JSONLSink) fileLocked(name string) (*jsonlFile, error) {
+    s.tick++
+    if jf, ok := s.files[name]; ok {
+        jf.used = s.tick
+        return jf, nil
+    }
+    if len(s.files) >= s.cfg.MaxOpen {
+        var lru string
+        for n, jf := range s.files {
+            if lru == "" || jf.used < s.files[lru].used {
+                lru = n
+            }
+        }
+        err := s.files[lru].close(false)
+        delete(s.files, lru)
+        if err != nil {
+            return nil, err
+        }
+    }
+    fp, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil {
+        return nil, err
+    }
+    jf := &jsonlFile{fp: fp, used: s.tick}
+    var w io.Writer = fp
+    if s.cfg.Gzip {
+        jf.gz = gzip.NewWriter(fp)
+        w = jf.gz
+    }
+    jf.buf = bufio.NewWriterSize(w, 64<<10)
+    s.files[name] = jf
+    return jf, nil
+}
+
+// flusher flushes written files every FlushInterval until Close.
+func (s *JSONLSink) flusher() {
+    defer close(s.done)
+    t := time.NewTicker(s.cfg.FlushInterval)
+    defer t.Stop()
+    for {
+        select {
+        case <-s.stop:
+            return
+        case <-t.C:
+            s.mu.Lock()
+            for name, jf := range s.files {
+                if jf.dirty {
+                    if err := jf.flush(); err != nil {
+                        fmt.Printf("[jsonl] %s: %v\n", name, err)
+                    }
+                }
+            }
+            s.mu.Unlock()
+        }
+    }
+}
+
+// Close stops the flusher and flushes, fsyncs and closes every open file. Later calls
+// return the first result.
+func (s *JSONLSink) Close() error {
+    s.closeOnce.Do(func() { s.closeErr = s.close() })
+    return s.closeErr
+}
+
+func (s *JSONLSink) close() error {
+    close(s.stop)
+    <-s.done
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var first error
+    for name, jf := range s.files {
+        if err := jf.close(true); err != nil && first == nil {
+            first = fmt.Errorf("%s: %w", name, err)
+        }
+        delete(s.files, name)
+    }
+    return first
+}
+
+// flush pushes buffered lines to the file; with gzip a sync flush makes them readable
+// before the file is closed.
+func (jf *jsonlFile) flush() error {
+    jf.dirty = false
+    if err := jf.buf.Flush(); err != nil {
+        return err
+    }
+    if jf.gz != nil {
+        return jf.gz.Flush()
+    }
+    return nil
+}
+
+// close flushes and closes the file, finishing the gzip stream; it is fsynced first when
+// fsync is set.
+func (jf *jsonlFile) close(fsync bool) error {
+    err := jf.buf.Flush()
+    if jf.gz != nil {
+        if gerr := jf.gz.Close(); err == nil {
+            err = gerr
+        }
+    }
+    if fsync {
+        if serr := jf.fp.Sync(); err == nil {
+            err = serr
+        }
+    }
+    if cerr := jf.fp.Close(); err == nil {
+        err = cerr
+    }
+    return err
+}
@@ -0,0 +1,102 @@
+package output
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func writeJSONL(t *testing.T, cfg JSONLConfig, fs ...*Finding) {
+    t.Helper()
+    s, err := NewJSONLSink(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, f := range fs {
+        if err := s.Write(f); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatalf("second Close: %v", err)
+    }
+}
+
+func findingPaths(fs []*Finding) []string {
+    var out []string
+    for _, f := range fs {
+        out = append(out, f.Host+f.Path)
+    }
+    return out
+}
+
+func TestJSONLSinkGzipSessionsAppend(t *testing.T) {
+    dir := t.TempDir()
+    cfg := JSONLConfig{Path: dir, Gzip: true}
+    writeJSONL(t, cfg, &Finding{Host: "https://a.example", Path: "/1/"}, &Finding{Host: "https://b.example", Path: "/1/"})
+    writeJSONL(t, cfg, &Finding{Host: "https://a.example", Path: "/2/"})
+
+    name := filepath.Join(dir, safeFilename("https://a.example")+".jsonl.gz")
+    fs, err := LoadFindings(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, want := findingPaths(fs), []string{"https://a.example/1/", "https://a.example/2/"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("two gzip members read as %v, want %v", got, want)
+    }
+    fs, err = LoadFindings(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(fs) != 3 {
+        t.Errorf("%d findings in the directory, want 3", len(fs))
+    }
+}
+
+func TestJSONLSinkFlushesAndEvicts(t *testing.T) {
+    dir := t.TempDir()
+    s, err := NewJSONLSink(JSONLConfig{Path: dir, Gzip: true, MaxOpen: 1, FlushInterval: 10 * time.Millisecond})
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.Write(&Finding{Host: "https://a.example", Path: "/1/"})
+    s.Write(&Finding{Host: "https://b.example", Path: "/1/"}) // closes a's file
+    s.Write(&Finding{Host: "https://a.example", Path: "/2/"}) // reopens it: a second member
+    time.Sleep(50 * time.Millisecond)
+    // a sync-flushed gzip stream is readable before Close
+    fs, err := LoadFindings(filepath.Join(dir, safeFilename("https://b.example")+".jsonl.gz"))
+    if err != nil || len(fs) != 1 {
+        t.Errorf("before Close: %d findings, %v", len(fs), err)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    fs, err = LoadFindings(filepath.Join(dir, safeFilename("https://a.example")+".jsonl.gz"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, want := findingPaths(fs), []string{"https://a.example/1/", "https://a.example/2/"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("after reopening: %v, want %v", got, want)
+    }
+}
+
+func TestJSONLSinkSingleFile(t *testing.T) {
+    name := filepath.Join(t.TempDir(), "sub", "all.jsonl")
+    writeJSONL(t, JSONLConfig{Path: name, Single: true}, &Finding{Host: "https://a.example", Path: "/1/"}, &Finding{Host: "https://b.example", Path: "/1/"})
+    writeJSONL(t, JSONLConfig{Path: name, Single: true}, &Finding{Host: "https://c.example", Path: "/1/"})
+    fs, err := LoadFindings(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(fs) != 3 {
+        t.Errorf("%d findings, want 3", len(fs))
+    }
+    if _, err := os.Stat(filepath.Join(filepath.Dir(name), safeFilename("https://a.example")+".jsonl")); err == nil {
+        t.Error("per-host file written in single-file mode")
+    }
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// LoadResults loads one result set: a JSONL file, an output directory, or a SQLite store
+// written by SQLiteSink. A store stands for its latest scan; "store.db@previous" selects
+// the scan before it and "store.db@ID" a scan by ID.
+func LoadResults(spec string) ([]*Finding, error) {
+    name, scan := spec, ""
+    if i := strings.LastIndex(spec, "@"); i > 0 && isSQLite(spec[:i]) {
+        name, scan = spec[:i], spec[i+1:]
+    }
+    if !isSQLite(name) {
+        return LoadFindings(name)
+    }
+    q := Query{Scan: -1}
+    switch scan {
+    case "", "latest":
+    case "previous":
+        q.Scan = -2
+    default:
+        id, err := strconv.ParseInt(scan, 10, 64)
+        if err != nil || id <= 0 {
+            return nil, fmt.Errorf("%s: invalid scan %q (want an ID, latest or previous)", name, scan)
+        }
+        q.Scan = id
+    }
+    return QueryFindings(name, q)
+}
+
+// isSQLite tells whether name is a SQLite database file.
+func isSQLite(name string) bool {
+    fp, err := os.Open(name)
+    if err != nil {
+        return false
+    }
+    defer fp.Close()
+    head := make([]byte, 16)
+    _, err = io.ReadFull(fp, head)
+    return err == nil && string(head) == "SQLite format 3\x00"
+}
+
+// LoadFindings reads findings back from JSONL files, plain or gzip-compressed. A
+// directory stands for all *.jsonl and *.jsonl.gz files in it (an output directory
+// written by JSONLSink).
+func LoadFindings(paths ...string) ([]*Finding, error) {
+    var out []*Finding
+    for _, p := range paths {
+        st, err := os.Stat(p)
+        if err != nil {
+            return nil, err
+        }
+        files := []string{p}
+        if st.IsDir() {
+            if files, err = filepath.Glob(filepath.Join(p, "*.jsonl")); err != nil {
+                return nil, err
+            }
+            gz, _ := filepath.Glob(filepath.Join(p, "*.jsonl.gz"))
+            files = append(files, gz...)
+            sort.Strings(files)
+        }
+        for _, name := range files {
+            fs, err := loadJSONL(name)
+            if err != nil {
+                return nil, err
+            }
+            out = append(out, fs...)
+        }
+    }
+    return out, nil
+}
+
+func loadJSONL(name string) ([]*Finding, error) {
+    fp, err := os.Open(name)
+    if err != nil {
+        return nil, err
+    }
+    defer fp.Close()
+    br := bufio.NewReader(fp)
+    var r io.Reader = br
+    if magic, _ := br.Peek(2); string(magic) == "\x1f\x8b" {
+        gz, err := gzip.NewReader(br)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", name, err)
+        }
+        defer gz.Close()
+        r = gz
+    }
+    var out []*Finding
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 64<<10), 64<<20)
+    for line := 1; sc.Scan(); line++ {
+        if len(sc.Bytes()) == 0 {
+            continue
+        }
+        f := new(Finding)
+        if err := json.Unmarshal(sc.Bytes(), f); err != nil {
+            return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+        }
+        out = append(out, f)
+    }
+    return out, sc.Err()
+}
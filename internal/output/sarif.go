@@ -0,0 +1,357 @@
+package output
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// SARIF logs are rewritten whole, so they are rewritten at most every sarifFlushInterval
+// or after sarifFlushEvery new findings, and on Close.
+const (
+    sarifFlushInterval = 5 * time.Second
+    sarifFlushEvery    = 500
+)
+
+// RuleDescriptions maps SARIF rule IDs ("module/signal") to a one-line description.
+// Modules fill it in for the signals they report; unknown rules get a generic text.
+var RuleDescriptions = map[string]string{}
+
+// SARIFSink collects findings into a SARIF 2.1.0 log with one rule per module/signal
+// and one result per signal that fired. The whole log is rewritten atomically every
+// sarifFlushInterval or sarifFlushEvery findings, so a crashed run still leaves a valid
+// file with the results up to the last rewrite (its invocation is marked unsuccessful
+// until Close).
+type SARIFSink struct {
+    Path    string
+    Version string // tool version reported in the log
+
+    mu      sync.Mutex
+    started time.Time
+    rules   []sarifRule
+    ruleIdx map[string]int
+    results []sarifResult
+    pending int // findings since the last rewrite
+    stop    chan struct{}
+    done    chan struct{}
+
+    closeOnce sync.Once
+    closeErr  error
+}
+
+// NewSARIFSink returns a sink writing the SARIF log to path and starts the periodic
+// rewrite.
+func NewSARIFSink(path, version string) (*SARIFSink, error) {
+    s := &SARIFSink{Path: path, Version: version, started: time.Now().UTC(), ruleIdx: make(map[string]int), stop: make(chan struct{}), done: make(chan struct{})}
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return nil, err
+    }
+    // an empty log up front, so a run without findings still produces a valid file
+    if err := s.flush(false); err != nil {
+        return nil, err
+    }
+    go s.flusher()
+    return s, nil
+}
+
+type sarifLog struct {
+    Schema  string     `json:"$schema"`
+    Version string     `json:"version"`
+    Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+    Tool        sarifTool         `json:"tool"`
+    Invocations []sarifInvocation `json:"invocations"`
+    Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+    Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+    Name    string      `json:"name"`
+    Version string      `json:"version,omitempty"`
+    Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+    ID               string          `json:"id"`
+    Name             string          `json:"name"`
+    ShortDescription sarifMessage    `json:"shortDescription"`
+    DefaultConfig    sarifRuleConfig `json:"defaultConfiguration"`
+    Properties       map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+    Level string `json:"level"`
+}
+
+type sarifInvocation struct {
+    ExecutionSuccessful bool   `json:"executionSuccessful"`
+    StartTimeUTC        string `json:"startTimeUtc"`
+    EndTimeUTC          string `json:"endTimeUtc,omitempty"`
+}
+
+type sarifMessage struct {
+    Text string `json:"text"`
+}
+
+type sarifResult struct {
+    RuleID              string            `json:"ruleId"`
+    RuleIndex           int               `json:"ruleIndex"`
+    Level               string            `json:"level"`
+    Message             sarifMessage      `json:"message"`
+    Locations           []sarifLocation   `json:"locations"`
+    PartialFingerprints map[string]string `json:"partialFingerprints"`
+    Properties          map[string]any    `json:"properties"`
+}
+
+type sarifLocation struct {
+    PhysicalLocation sarifPhysical `json:"physicalLocation"`
+}
+
+type sarifPhysical struct {
+    ArtifactLocation sarifArtifact `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+    URI string `json:"uri"`
+}
+
+func (s *SARIFSink) Write(f *Finding) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    signals := FindingSignals(f)
+    if len(signals) == 0 {
+        signals = []string{"finding"}
+    }
+    for _, sig := range signals {
+        id := f.Module + "/" + sig
+        idx, ok := s.ruleIdx[id]
+        if !ok {
+            idx = len(s.rules)
+            s.ruleIdx[id] = idx
+            s.rules = append(s.rules, newSARIFRule(f.Module, sig))
+        }
+        msg := fmt.Sprintf("%s: %s signal at %s (payload %q, status %d)", f.Module, sig, f.URL, f.Payload, f.Status)
+        if len(signals) > 1 {
+            msg += "; all signals: " + strings.Join(signals, ", ")
+        }
+        sum := sha256.Sum256([]byte(strings.Join([]string{id, f.Host, f.Path, f.Payload, forcedProtocol(f)}, "\x00")))
+        s.results = append(s.results, sarifResult{
+            RuleID:              id,
+            RuleIndex:           idx,
+            Level:               sarifLevel(f.Confidence),
+            Message:             sarifMessage{Text: msg},
+            Locations:           []sarifLocation{{PhysicalLocation: sarifPhysical{ArtifactLocation: sarifArtifact{URI: sarifURI(f.URL)}}}},
+            PartialFingerprints: map[string]string{"pohekFinding/v1": hex.EncodeToString(sum[:16])},
+            Properties:          sarifEvidence(f, signals),
+        })
+    }
+    if s.pending++; s.pending < sarifFlushEvery {
+        return nil
+    }
+    return s.flush(false)
+}
+
+// flusher rewrites the log every sarifFlushInterval while findings are pending.
+func (s *SARIFSink) flusher() {
+    defer close(s.done)
+    t := time.NewTicker(sarifFlushInterval)
+    defer t.Stop()
+    for {
+        select {
+        case <-s.stop:
+            return
+        case <-t.C:
+            s.mu.Lock()
+            if s.pending > 0 {
+                if err := s.flush(false); err != nil {
+                    fmt.Printf("[sarif] %s: %v\n", s.Path, err)
+                }
+            }
+            s.mu.Unlock()
+        }
+    }
+}
+
+// Close stops the periodic rewrite and rewrites the log with the invocation marked as
+// finished. Later calls return the first result.
+func (s *SARIFSink) Close() error {
+    s.closeOnce.Do(func() { s.closeErr = s.close() })
+    return s.closeErr
+}
+
+func (s *SARIFSink) close() error {
+    close(s.stop)
+    <-s.done
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.flush(true)
+}
+
+// sarifURI makes a finding URL a valid URI reference for artifactLocation.uri: payload
+// URLs may carry spaces, backslashes, control or non-ASCII bytes, which are
+// percent-encoded, as is any '#' after the first; existing %XX escapes are kept.
+func sarifURI(raw string) string {
+    const hexDigits = "0123456789ABCDEF"
+    isHex := func(c byte) bool {
+        return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+    }
+    var b strings.Builder
+    fragment := false
+    for i := 0; i < len(raw); i++ {
+        c := raw[i]
+        switch {
+        case c == '%' && i+2 < len(raw) && isHex(raw[i+1]) && isHex(raw[i+2]):
+            b.WriteByte(c)
+        case c == '#' && !fragment:
+            fragment = true
+            b.WriteByte(c)
+        case c != '%' && c != '#' && c > ' ' && c < 0x7f && !strings.ContainsRune("\\\"<>^`{|}", rune(c)):
+            b.WriteByte(c)
+        default:
+            b.WriteByte('%')
+            b.WriteByte(hexDigits[c>>4])
+            b.WriteByte(hexDigits[c&15])
+        }
+    }
+    return b.String()
+}
+
+func newSARIFRule(module, signal string) sarifRule {
+    id := module + "/" + signal
+    desc, ok := RuleDescriptions[id]
+    if !ok {
+        desc = fmt.Sprintf("%s finding with the %s signal", module, signal)
+    }
+    return sarifRule{
+        ID:               id,
+        Name:             module + "-" + strings.ReplaceAll(signal, "_", "-"),
+        ShortDescription: sarifMessage{Text: desc},
+        DefaultConfig:    sarifRuleConfig{Level: "warning"},
+        Properties:       map[string]any{"tags": []string{"security", module}},
+    }
+}
+
+// sarifLevel maps a finding confidence to a SARIF result level.
+func sarifLevel(confidence string) string {
+    switch confidence {
+    case ConfidenceHigh:
+        return "error"
+    case ConfidenceLow:
+        return "note"
+    }
+    return "warning"
+}
+
+// sarifEvidence is the property bag of a result: everything needed to judge and
+// reproduce the finding without the JSONL output.
+func sarifEvidence(f *Finding, signals []string) map[string]any {
+    p := map[string]any{
+        "host":    f.Host,
+        "path":    f.Path,
+        "payload": f.Payload,
+        "url":     f.URL,
+        "signals": signals,
+        "status":  f.Status,
+    }
+    add := func(k string, v string) {
+        if v != "" {
+            p[k] = v
+        }
+    }
+    add("confidence", f.Confidence)
+    add("server", f.Server)
+    add("contentType", f.ContentType)
+    add("protocol", f.Protocol)
+    add("request", f.Request)
+    add("bodyDiff", f.BodyDiff)
+    if len(f.Notes) > 0 {
+        p["notes"] = f.Notes
+    }
+    if len(f.Payloads) > 0 {
+        p["payloads"] = f.Payloads
+    }
+    if len(f.Paths) > 0 {
+        p["paths"] = f.Paths
+    }
+    if len(f.HeadersAdded) > 0 {
+        p["headersAdded"] = f.HeadersAdded
+    }
+    if len(f.HeadersRemoved) > 0 {
+        p["headersRemoved"] = f.HeadersRemoved
+    }
+    if len(f.Exchanges) > 0 {
+        p["exchanges"] = f.Exchanges
+    }
+    if len(f.Evidence) > 0 {
+        p["evidence"] = f.Evidence
+    }
+    return p
+}
+
+// flush writes the log to a temporary file next to Path and renames it over Path, so
+// readers never see a partial log. The caller holds mu (or is the constructor).
+func (s *SARIFSink) flush(done bool) error {
+    s.pending = 0
+    inv := sarifInvocation{ExecutionSuccessful: done, StartTimeUTC: s.started.Format(time.RFC3339)}
+    if done {
+        inv.EndTimeUTC = time.Now().UTC().Format(time.RFC3339)
+    }
+    results := s.results
+    if results == nil {
+        results = []sarifResult{}
+    }
+    rules := s.rules
+    if rules == nil {
+        rules = []sarifRule{}
+    }
+    log := sarifLog{
+        Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+        Version: "2.1.0",
+        Runs: []sarifRun{{
+            Tool:        sarifTool{Driver: sarifDriver{Name: "pohek", Version: s.Version, Rules: rules}},
+            Invocations: []sarifInvocation{inv},
+            Results:     results,
+        }},
+    }
+    data, err := json.MarshalIndent(log, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeAtomic(s.Path, data)
+}
+
+// writeAtomic replaces name with data via a synced temporary file in the same directory.
+func writeAtomic(name string, data []byte) error {
+    fp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+    if err != nil {
+        return err
+    }
+    tmp := fp.Name()
+    if _, err = fp.Write(data); err == nil {
+        err = fp.Sync()
+    }
+    if cerr := fp.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Chmod(tmp, 0o644)
+    }
+    if err == nil {
+        err = os.Rename(tmp, name)
+    }
+    if err != nil {
+        os.Remove(tmp)
+    }
+    return err
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+    "encoding/json"
+    "net/url"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestSARIFURI(t *testing.T) {
+    tests := []struct {
+        raw  string
+        want string
+    }{
+        {"https://h/a/b?c=d", "https://h/a/b?c=d"},
+        {"https://h/a b", "https://h/a%20b"},
+        {`https://h/..\..\x`, "https://h/..%5C..%5Cx"},
+        {"https://h/%2e%2e/x", "https://h/%2e%2e/x"},
+        {"https://h/100%", "https://h/100%25"},
+        {"https://h/%zz", "https://h/%25zz"},
+        {"https://h/caf\xc3\xa9", "https://h/caf%C3%A9"},
+        {"https://h/x\r\n", "https://h/x%0D%0A"},
+        {"https://h/a#b#c", "https://h/a#b%23c"},
+        {"https://h/<{|}>", "https://h/%3C%7B%7C%7D%3E"},
+    }
+    for _, tt := range tests {
+        got := sarifURI(tt.raw)
+        if got != tt.want {
+            t.Errorf("sarifURI(%q) = %q, want %q", tt.raw, got, tt.want)
+        }
+        if _, err := url.Parse(got); err != nil {
+            t.Errorf("sarifURI(%q) = %q does not parse: %v", tt.raw, got, err)
+        }
+    }
+}
+
+func TestSARIFSinkRewritesOnClose(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "out.sarif")
+    s, err := NewSARIFSink(path, "test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    read := func() sarifLog {
+        t.Helper()
+        data, err := os.ReadFile(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var log sarifLog
+        if err := json.Unmarshal(data, &log); err != nil {
+            t.Fatal(err)
+        }
+        return log
+    }
+    for i := 0; i < 3; i++ {
+        if err := s.Write(&Finding{Module: "scpt", Host: "h", URL: "https://h/a b", Payload: "x"}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if n := len(read().Runs[0].Results); n != 0 {
+        t.Errorf("%d results before the first rewrite, want 0 (the log is not rewritten per finding)", n)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.Close(); err != nil {
+        t.Fatalf("second Close: %v", err)
+    }
+    run := read().Runs[0]
+    if len(run.Results) != 3 || !run.Invocations[0].ExecutionSuccessful {
+        t.Fatalf("after Close: %d results, successful %v", len(run.Results), run.Invocations[0].ExecutionSuccessful)
+    }
+    if uri := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "https://h/a%20b" {
+        t.Errorf("uri = %q", uri)
+    }
+}
@@ -1,10 +1,9 @@
 package output
 
 import (
-    "encoding/json"
     "fmt"
-    "os"
-    "path/filepath"
+    "sort"
+    "strings"
     "time"
     "sync"
 )
@@ -16,12 +15,62 @@ type Finding struct {
     Host        string            `json:"host"`
     Path        string            `json:"path"`
     Payload     string            `json:"payload"`
+    Payloads    []string          `json:"payloads,omitempty"` // every payload merged into this finding by AggregateSink
+    Paths       []string          `json:"paths,omitempty"`    // sibling paths collapsed into Path by AggregateSink
     URL         string            `json:"url"`
     Signals     map[string]bool   `json:"signals"`
     Notes       []string          `json:"notes"`
     Status      int               `json:"status"`
     Server      string            `json:"server"`
     ContentType string            `json:"content_type"`
+    Protocol    string            `json:"protocol,omitempty"` // HTTP version the request was sent with
+    Request     string            `json:"request,omitempty"` // raw request as sent on the wire
+    Curl        string            `json:"curl,omitempty"`    // curl command reproducing Request byte for byte
+    Python      string            `json:"python,omitempty"`  // Python socket script sending Request byte for byte
+    Confidence  string            `json:"confidence,omitempty"` // ConfidenceLow, ConfidenceMedium or ConfidenceHigh
+    Fingerprint string            `json:"fingerprint,omitempty"` // stable across scans, see Fingerprint
+
+    ProtocolForced   bool       `json:"protocol_forced,omitempty"`   // Protocol was chosen (--proto, --scpt-protos, request file), not negotiated
+    Exchanges        []uint64   `json:"exchanges,omitempty"`         // traffic log entries behind the finding (hit and baselines)
+    HeadersAdded     []string   `json:"headers_added,omitempty"`     // "+name" / "+set-cookie:name" vs all baselines
+    HeadersRemoved   []string   `json:"headers_removed,omitempty"`   // "-name" / "-set-cookie:name" vs all baselines
+    HeadersCasing    []string   `json:"headers_casing,omitempty"`    // names cased differently than in all baselines (raw transport only)
+    HeadersReordered bool       `json:"headers_reordered,omitempty"` // shared headers in another order than in all baselines (raw transport only)
+    Responses        []Snapshot `json:"responses,omitempty"`         // the hit and the baselines it was compared with
+    Evidence         []Evidence `json:"evidence,omitempty"`          // stored raw exchanges, see EvidenceStore
+    BodyDiff         string     `json:"body_diff,omitempty"`         // traversal body compared with each baseline
+}
+
+// Snapshot is a short record of one response shown next to a finding, e.g. the traversal
+// hit beside its parent and non-existent baselines.
+type Snapshot struct {
+    Role        string   `json:"role"` // "traversal", "parent", "nonexistent", ...
+    URL         string   `json:"url"`
+    Status      int      `json:"status"`
+    Server      string   `json:"server,omitempty"`
+    ContentType string   `json:"content_type,omitempty"`
+    BodySize    int64    `json:"body_size"`
+    Headers     []string `json:"headers,omitempty"` // "Name: value"
+    Body        string   `json:"body,omitempty"`    // leading part of a text body
+}
+
+// Finding confidence levels, set by the module that reports the finding.
+const (
+    ConfidenceLow    = "low"
+    ConfidenceMedium = "medium"
+    ConfidenceHigh   = "high"
+)
+
+// FindingSignals returns the signals set on f, sorted.
+func FindingSignals(f *Finding) []string {
+    var out []string
+    for name, on := range f.Signals {
+        if on {
+            out = append(out, name)
+        }
+    }
+    sort.Strings(out)
+    return out
 }
 
 // Sink is a destination for findings (stdout, file, JSONL, etc.).
@@ -29,6 +78,43 @@ type Sink interface {
     Write(*Finding) error
 }
 
+// Closer is implemented by sinks that need to finish their output at the end of a run.
+type Closer interface {
+    Close() error
+}
+
+// Close closes s if it implements Closer.
+func Close(s Sink) error {
+    if c, ok := s.(Closer); ok {
+        return c.Close()
+    }
+    return nil
+}
+
+// MultiSink writes every finding to all of its sinks; the first error is returned after
+// all sinks have been tried.
+type MultiSink []Sink
+
+func (m MultiSink) Write(f *Finding) error {
+    var first error
+    for _, s := range m {
+        if err := s.Write(f); err != nil && first == nil {
+            first = err
+        }
+    }
+    return first
+}
+
+func (m MultiSink) Close() error {
+    var first error
+    for _, s := range m {
+        if err := Close(s); err != nil && first == nil {
+            first = err
+        }
+    }
+    return first
+}
+
 // SafeSink wraps another Sink and serializes concurrent Write calls.
 type SafeSink struct {
     mu    sync.Mutex
@@ -44,35 +130,57 @@ func (s *SafeSink) Write(f *Finding) error {
     return s.Inner.Write(f)
 }
 
-// StdoutSink prints findings to stdout in a compact textual form.
-type StdoutSink struct{}
+func (s *SafeSink) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return Close(s.Inner)
+}
 
-func (s StdoutSink) Write(f *Finding) error {
-    fmt.Printf("[+] %s %s payload=%q status=%d signals=%v\n", f.Host, f.Path, f.Payload, f.Status, f.Signals)
-    return nil
+// TapSink calls Tap with every finding before passing it to Inner, e.g. to export the
+// traffic behind a finding.
+type TapSink struct {
+    Inner Sink
+    Tap   func(*Finding)
 }
 
-// JSONLSink writes findings to a JSONL file per host inside OutputDir.
-type JSONLSink struct{
-    OutputDir string
+func (s TapSink) Write(f *Finding) error {
+    s.Tap(f)
+    return s.Inner.Write(f)
 }
 
-func (s JSONLSink) Write(f *Finding) error {
-    if s.OutputDir == "" || s.OutputDir == "no.no" {
-        // If no directory provided, fallback to stdout
-        return StdoutSink{}.Write(f)
+func (s TapSink) Close() error { return Close(s.Inner) }
+
+// StdoutSink prints findings to stdout in a compact textual form.
+type StdoutSink struct{}
+
+func (s StdoutSink) Write(f *Finding) error {
+    if len(f.Payloads) > 1 || len(f.Paths) > 0 {
+        fmt.Printf("[+] %s %s payloads=%q paths=%q status=%d signals=%v\n", f.Host, f.Path, f.Payloads, f.Paths, f.Status, f.Signals)
+    } else {
+        fmt.Printf("[+] %s %s payload=%q status=%d signals=%v\n", f.Host, f.Path, f.Payload, f.Status, f.Signals)
+    }
+    if f.Request != "" {
+        fmt.Printf("    raw request:\n%s", indent(strings.TrimRight(f.Request, "\r\n")+"\n", "      "))
     }
-    if err := os.MkdirAll(s.OutputDir, 0o755); err != nil {
-        return err
+    if f.Curl != "" {
+        fmt.Printf("    curl:\n      %s\n", f.Curl)
     }
-    filename := filepath.Join(s.OutputDir, safeFilename(f.Host)+".jsonl")
-    fp, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-    if err != nil {
-        return err
+    if f.Python != "" {
+        fmt.Printf("    python:\n%s", indent(f.Python, "      "))
+    }
+    return nil
+}
+
+// indent prefixes every line of s.
+func indent(s, prefix string) string {
+    lines := strings.SplitAfter(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+    var b strings.Builder
+    for _, l := range lines {
+        if l != "" {
+            b.WriteString(prefix + l)
+        }
     }
-    defer fp.Close()
-    enc := json.NewEncoder(fp)
-    return enc.Encode(f)
+    return b.String()
 }
 
 func safeFilename(host string) string {
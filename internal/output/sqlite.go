@@ -0,0 +1,320 @@
+package output
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    _ "modernc.org/sqlite" // pure-Go driver, registered as "sqlite"
+)
+
+// sqliteSchema is created on open; statements are idempotent so one database file can
+// collect any number of scans.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+    id       INTEGER PRIMARY KEY,
+    started  TEXT NOT NULL,
+    finished TEXT,
+    label    TEXT
+);
+CREATE TABLE IF NOT EXISTS targets (
+    id      INTEGER PRIMARY KEY,
+    scan_id INTEGER NOT NULL REFERENCES scans(id),
+    host    TEXT NOT NULL,
+    path    TEXT NOT NULL,
+    UNIQUE (scan_id, host, path)
+);
+CREATE TABLE IF NOT EXISTS findings (
+    id           INTEGER PRIMARY KEY,
+    scan_id      INTEGER NOT NULL REFERENCES scans(id),
+    target_id    INTEGER NOT NULL REFERENCES targets(id),
+    ts           TEXT NOT NULL,
+    module       TEXT NOT NULL,
+    host         TEXT NOT NULL,
+    path         TEXT NOT NULL,
+    payload      TEXT NOT NULL,
+    url          TEXT NOT NULL,
+    status       INTEGER NOT NULL,
+    server       TEXT,
+    content_type TEXT,
+    protocol     TEXT,
+    confidence   TEXT,
+    data         TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS finding_signals (
+    finding_id INTEGER NOT NULL REFERENCES findings(id),
+    signal     TEXT NOT NULL,
+    PRIMARY KEY (finding_id, signal)
+);
+CREATE TABLE IF NOT EXISTS requests (
+    id           INTEGER PRIMARY KEY,
+    finding_id   INTEGER NOT NULL REFERENCES findings(id),
+    role         TEXT NOT NULL,
+    url          TEXT NOT NULL,
+    raw          TEXT,
+    status       INTEGER,
+    server       TEXT,
+    content_type TEXT,
+    body_size    INTEGER,
+    headers      TEXT
+);
+CREATE INDEX IF NOT EXISTS findings_host ON findings(host);
+CREATE INDEX IF NOT EXISTS findings_ts ON findings(ts);
+CREATE INDEX IF NOT EXISTS findings_module ON findings(module);
+CREATE INDEX IF NOT EXISTS findings_status ON findings(status);
+CREATE INDEX IF NOT EXISTS findings_scan ON findings(scan_id);
+CREATE INDEX IF NOT EXISTS findings_key ON findings(module, host, path, payload);
+CREATE INDEX IF NOT EXISTS finding_signals_signal ON finding_signals(signal);
+CREATE INDEX IF NOT EXISTS requests_finding ON requests(finding_id);
+`
+
+// sqliteTime is the stored timestamp format: UTC and fixed width, so text comparison
+// orders correctly.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// openSQLite opens (and if needed creates) a results database.
+func openSQLite(name string) (*sql.DB, error) {
+    if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+        return nil, err
+    }
+    db, err := sql.Open("sqlite", name+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)")
+    if err != nil {
+        return nil, err
+    }
+    db.SetMaxOpenConns(1)
+    if _, err := db.Exec(sqliteSchema); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    return db, nil
+}
+
+// SQLiteSink stores findings in a SQLite database, one scans row per run. Each finding
+// is committed on its own, so an interrupted scan keeps what it found.
+type SQLiteSink struct {
+    db      *sql.DB
+    scan    int64
+    targets map[[2]string]int64
+}
+
+// NewSQLiteSink opens the database at name and registers a new scan with the given
+// label (e.g. the target and wordlist). Wrap it in a SafeSink for concurrent writers.
+func NewSQLiteSink(name, label string) (*SQLiteSink, error) {
+    db, err := openSQLite(name)
+    if err != nil {
+        return nil, err
+    }
+    res, err := db.Exec(`INSERT INTO scans (started, label) VALUES (?, ?)`, time.Now().UTC().Format(sqliteTime), label)
+    if err != nil {
+        db.Close()
+        return nil, err
+    }
+    s := &SQLiteSink{db: db, targets: make(map[[2]string]int64)}
+    if s.scan, err = res.LastInsertId(); err != nil {
+        db.Close()
+        return nil, err
+    }
+    return s, nil
+}
+
+func (s *SQLiteSink) Write(f *Finding) error {
+    data, err := json.Marshal(f)
+    if err != nil {
+        return err
+    }
+    tx, err := s.db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    key := [2]string{f.Host, f.Path}
+    target, ok := s.targets[key]
+    if !ok {
+        if _, err := tx.Exec(`INSERT OR IGNORE INTO targets (scan_id, host, path) VALUES (?, ?, ?)`, s.scan, f.Host, f.Path); err != nil {
+            return err
+        }
+        if err := tx.QueryRow(`SELECT id FROM targets WHERE scan_id = ? AND host = ? AND path = ?`, s.scan, f.Host, f.Path).Scan(&target); err != nil {
+            return err
+        }
+    }
+    ts := f.Timestamp
+    if ts.IsZero() {
+        ts = time.Now()
+    }
+    res, err := tx.Exec(`INSERT INTO findings (scan_id, target_id, ts, module, host, path, payload, url, status, server, content_type, protocol, confidence, data)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        s.scan, target, ts.UTC().Format(sqliteTime), f.Module, f.Host, f.Path, f.Payload, f.URL, f.Status, f.Server, f.ContentType, f.Protocol, f.Confidence, string(data))
+    if err != nil {
+        return err
+    }
+    id, err := res.LastInsertId()
+    if err != nil {
+        return err
+    }
+    for name, on := range f.Signals {
+        if on {
+            if _, err := tx.Exec(`INSERT INTO finding_signals (finding_id, signal) VALUES (?, ?)`, id, name); err != nil {
+                return err
+            }
+        }
+    }
+    // the raw request belongs to the traversal response; baselines only have snapshots
+    hasHit := false
+    for _, r := range f.Responses {
+        raw := ""
+        if r.Role == "traversal" {
+            raw, hasHit = f.Request, true
+        }
+        headers, _ := json.Marshal(r.Headers)
+        if _, err := tx.Exec(`INSERT INTO requests (finding_id, role, url, raw, status, server, content_type, body_size, headers) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+            id, r.Role, r.URL, raw, r.Status, r.Server, r.ContentType, r.BodySize, string(headers)); err != nil {
+            return err
+        }
+    }
+    if !hasHit {
+        if _, err := tx.Exec(`INSERT INTO requests (finding_id, role, url, raw, status, server, content_type) VALUES (?, 'traversal', ?, ?, ?, ?, ?)`,
+            id, f.URL, f.Request, f.Status, f.Server, f.ContentType); err != nil {
+            return err
+        }
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    s.targets[key] = target
+    return nil
+}
+
+// Close marks the scan as finished and closes the database.
+func (s *SQLiteSink) Close() error {
+    _, err := s.db.Exec(`UPDATE scans SET finished = ? WHERE id = ?`, time.Now().UTC().Format(sqliteTime), s.scan)
+    if cerr := s.db.Close(); err == nil {
+        err = cerr
+    }
+    return err
+}
+
+// Query selects findings from a results database. Zero values do not filter.
+type Query struct {
+    Host   string    // substring of the finding's host (origin URL)
+    Module string
+    Signal string    // the finding must have this signal set
+    Status int
+    Since  time.Time // found at or after
+    Until  time.Time // found before
+    Scan   int64     // only this scan; -1 = the latest scan, -2 the one before, ...
+    New    bool      // only findings whose module/host/path/payload was not found before Since (or before their scan)
+    Limit  int
+}
+
+// QueryFindings runs q against the database at name, oldest findings first.
+func QueryFindings(name string, q Query) ([]*Finding, error) {
+    if _, err := os.Stat(name); err != nil {
+        return nil, err
+    }
+    db, err := openSQLite(name)
+    if err != nil {
+        return nil, err
+    }
+    defer db.Close()
+
+    var where []string
+    var args []any
+    if q.Host != "" {
+        where, args = append(where, "instr(f.host, ?) > 0"), append(args, q.Host)
+    }
+    if q.Module != "" {
+        where, args = append(where, "f.module = ?"), append(args, q.Module)
+    }
+    if q.Signal != "" {
+        where, args = append(where, "EXISTS (SELECT 1 FROM finding_signals s WHERE s.finding_id = f.id AND s.signal = ?)"), append(args, q.Signal)
+    }
+    if q.Status != 0 {
+        where, args = append(where, "f.status = ?"), append(args, q.Status)
+    }
+    if !q.Since.IsZero() {
+        where, args = append(where, "f.ts >= ?"), append(args, q.Since.UTC().Format(sqliteTime))
+    }
+    if !q.Until.IsZero() {
+        where, args = append(where, "f.ts < ?"), append(args, q.Until.UTC().Format(sqliteTime))
+    }
+    switch {
+    case q.Scan > 0:
+        where, args = append(where, "f.scan_id = ?"), append(args, q.Scan)
+    case q.Scan < 0:
+        where, args = append(where, "f.scan_id = (SELECT id FROM scans ORDER BY id DESC LIMIT 1 OFFSET ?)"), append(args, -q.Scan-1)
+    }
+    if q.New {
+        // "before" is Since when given, otherwise the start of the finding's own scan
+        cut := "(SELECT started FROM scans WHERE id = f.scan_id)"
+        if !q.Since.IsZero() {
+            cut = "?"
+            args = append(args, q.Since.UTC().Format(sqliteTime))
+        }
+        where = append(where, `NOT EXISTS (SELECT 1 FROM findings o WHERE o.module = f.module AND o.host = f.host
+            AND o.path = f.path AND o.payload = f.payload AND o.ts < `+cut+`)`)
+    }
+    stmt := "SELECT f.data FROM findings f"
+    if len(where) > 0 {
+        stmt += " WHERE " + strings.Join(where, " AND ")
+    }
+    stmt += " ORDER BY f.ts, f.id"
+    if q.Limit > 0 {
+        stmt += fmt.Sprintf(" LIMIT %d", q.Limit)
+    }
+    rows, err := db.Query(stmt, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    var out []*Finding
+    for rows.Next() {
+        var data string
+        if err := rows.Scan(&data); err != nil {
+            return nil, err
+        }
+        f := new(Finding)
+        if err := json.Unmarshal([]byte(data), f); err != nil {
+            return nil, err
+        }
+        out = append(out, f)
+    }
+    return out, rows.Err()
+}
+
+// ParseSince parses a point in time for Query.Since/Until: a date (2006-01-02, local
+// time), an RFC 3339 timestamp, a duration back from now ("36h", "7d"), or a weekday
+// ("tuesday" = the most recent Tuesday before today, at midnight).
+func ParseSince(s string, now time.Time) (time.Time, error) {
+    s = strings.TrimSpace(strings.ToLower(s))
+    if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+        return t, nil
+    }
+    if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+        return t, nil
+    }
+    if n := len(s); n > 1 && s[n-1] == 'd' {
+        var days int
+        if _, err := fmt.Sscanf(s[:n-1], "%d", &days); err == nil && fmt.Sprint(days) == s[:n-1] {
+            return now.AddDate(0, 0, -days), nil
+        }
+    }
+    if d, err := time.ParseDuration(s); err == nil {
+        return now.Add(-d), nil
+    }
+    for wd := time.Sunday; wd <= time.Saturday; wd++ {
+        if s == strings.ToLower(wd.String()) {
+            back := (int(now.Weekday()) - int(wd) + 7) % 7
+            if back == 0 {
+                back = 7
+            }
+            y, m, d := now.AddDate(0, 0, -back).Date()
+            return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("invalid time %q (want 2006-01-02, RFC 3339, 36h, 7d or a weekday)", s)
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestParseSince(t *testing.T) {
+    loc := time.FixedZone("X", 2*3600)
+    now := time.Date(2026, 10, 15, 13, 30, 0, 0, loc) // a Thursday
+    tests := []struct {
+        in      string
+        want    time.Time
+        wantErr bool
+    }{
+        {in: "2026-10-01", want: time.Date(2026, 10, 1, 0, 0, 0, 0, loc)},
+        {in: "2026-10-01T08:00:00Z", want: time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)},
+        {in: "2026-10-01t08:00:00+02:00", want: time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC)},
+        {in: "36h", want: now.Add(-36 * time.Hour)},
+        {in: "90m", want: now.Add(-90 * time.Minute)},
+        {in: "7d", want: now.AddDate(0, 0, -7)},
+        {in: " Tuesday ", want: time.Date(2026, 10, 13, 0, 0, 0, 0, loc)},
+        {in: "thursday", want: time.Date(2026, 10, 8, 0, 0, 0, 0, loc)},
+        {in: "friday", want: time.Date(2026, 10, 9, 0, 0, 0, 0, loc)},
+        {in: "d", wantErr: true},
+        {in: "+7d", wantErr: true},
+        {in: "yesterday", wantErr: true},
+        {in: "2026-13-01", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := ParseSince(tt.in, now)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("ParseSince(%q) = %v, want error", tt.in, got)
+            }
+            continue
+        }
+        if err != nil || !got.Equal(tt.want) {
+            t.Errorf("ParseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+        }
+    }
+}
+
+func TestQueryFindings(t *testing.T) {
+    db := filepath.Join(t.TempDir(), "results.db")
+    old := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    scan := func(label string, fs ...*Finding) {
+        t.Helper()
+        s, err := NewSQLiteSink(db, label)
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, f := range fs {
+            if err := s.Write(f); err != nil {
+                t.Fatal(err)
+            }
+        }
+        if err := s.Close(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    finding := func(host, path string, status int, ts time.Time, signals ...string) *Finding {
+        f := &Finding{Module: "scpt", Host: host, Path: path, Payload: "..;/", Status: status, Timestamp: ts, Signals: map[string]bool{}}
+        for _, s := range signals {
+            f.Signals[s] = true
+        }
+        return f
+    }
+    scan("first",
+        finding("https://a.example", "/admin", 200, old, "status"),
+        finding("https://b.example", "/x", 403, old.Add(time.Hour), "headers"))
+    time.Sleep(10 * time.Millisecond) // the second scan starts after the first one's findings
+    scan("second",
+        finding("https://a.example", "/admin", 200, time.Time{}, "status"),
+        finding("https://c.example", "/y", 200, time.Time{}, "status", "headers"))
+
+    tests := []struct {
+        name string
+        q    Query
+        want []string // host+path
+    }{
+        {"all, oldest first", Query{}, []string{"https://a.example/admin", "https://b.example/x", "https://a.example/admin", "https://c.example/y"}},
+        {"host substring", Query{Host: "a.ex"}, []string{"https://a.example/admin", "https://a.example/admin"}},
+        {"signal", Query{Signal: "headers"}, []string{"https://b.example/x", "https://c.example/y"}},
+        {"status", Query{Status: 403}, []string{"https://b.example/x"}},
+        {"module", Query{Module: "other"}, nil},
+        {"first scan", Query{Scan: 1}, []string{"https://a.example/admin", "https://b.example/x"}},
+        {"latest scan", Query{Scan: -1}, []string{"https://a.example/admin", "https://c.example/y"}},
+        {"scan before the latest", Query{Scan: -2}, []string{"https://a.example/admin", "https://b.example/x"}},
+        {"since", Query{Since: old.Add(30 * time.Minute)}, []string{"https://b.example/x", "https://a.example/admin", "https://c.example/y"}},
+        {"until", Query{Until: old.Add(30 * time.Minute)}, []string{"https://a.example/admin"}},
+        {"limit", Query{Limit: 1}, []string{"https://a.example/admin"}},
+        {"new in the latest scan", Query{Scan: -1, New: true}, []string{"https://c.example/y"}},
+        {"new since", Query{Since: old.Add(30 * time.Minute), New: true}, []string{"https://b.example/x", "https://c.example/y"}},
+    }
+    for _, tt := range tests {
+        fs, err := QueryFindings(db, tt.q)
+        if err != nil {
+            t.Fatalf("%s: %v", tt.name, err)
+        }
+        var got []string
+        for _, f := range fs {
+            got = append(got, f.Host+f.Path)
+        }
+        if !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+        }
+    }
+
+    if _, err := QueryFindings(filepath.Join(t.TempDir(), "missing.db"), Query{}); err == nil {
+        t.Error("QueryFindings on a missing database: want error")
+    }
+}
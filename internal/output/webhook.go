@@ -0,0 +1,272 @@
+package output
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "text/template"
+    "time"
+)
+
+// webhookQueue is how many findings wait for the sender before new ones are dropped.
+const webhookQueue = 1000
+
+// Webhook payload presets for WebhookConfig.Preset.
+const (
+    WebhookJSON       = "json"       // {"count": N, "findings": [...]}, the findings as written to JSONL
+    WebhookSlack      = "slack"      // {"text": "..."}
+    WebhookMattermost = "mattermost" // {"text": "...", "username": "pohek"}
+    WebhookDiscord    = "discord"    // {"content": "..."}, cut to Discord's 2000 characters
+)
+
+var webhookPresets = map[string]string{
+    WebhookJSON:       `{"count": {{.Count}}, "findings": {{json .Findings}}}`,
+    WebhookSlack:      `{"text": {{json .Text}}}`,
+    WebhookMattermost: `{"text": {{json .Text}}, "username": "pohek"}`,
+    WebhookDiscord:    `{"content": {{json (trunc 1990 .Text)}}}`,
+}
+
+// WebhookConfig configures a WebhookSink. Zero values get the defaults noted per field.
+type WebhookConfig struct {
+    URL           string
+    Preset        string        // one of the Webhook* presets (default json); ignored with Template
+    Template      string        // text/template file rendering the request body from WebhookBatch
+    MinConfidence string        // findings below this confidence are dropped (default high)
+    BatchSize     int           // findings per request (default 10)
+    BatchWait     time.Duration // longest wait for a batch to fill (default 30s)
+    Interval      time.Duration // minimum time between requests (default 2s)
+    Retries       int           // retries of a failed request (default 3, negative = none; backoff 1s, 2s, 4s...)
+    Timeout       time.Duration // per request (default 10s)
+}
+
+// WebhookBatch is the data a payload template is executed with. Text is a readable
+// summary, one line per finding.
+type WebhookBatch struct {
+    Count    int
+    Findings []*Finding
+    Text     string
+}
+
+// WebhookSink POSTs batches of findings as templated JSON to a URL (Slack, Discord and
+// Mattermost incoming webhooks via presets). Findings are queued and sent by a
+// background goroutine, so a slow or failing endpoint does not stall the scan: when the
+// queue is full, findings are dropped and counted rather than waited for. Close sends what
+// is left and reports the dropped findings.
+type WebhookSink struct {
+    cfg     WebhookConfig
+    tmpl    *template.Template
+    client  *http.Client
+    queue   chan *Finding
+    done    chan struct{}
+    dropped int64         // atomic
+    backoff time.Duration // first retry delay, doubled per retry
+
+    closeOnce sync.Once
+    closeErr  error
+}
+
+// NewWebhookSink validates cfg and starts the sender.
+func NewWebhookSink(cfg WebhookConfig) (*WebhookSink, error) {
+    if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+        return nil, fmt.Errorf("webhook URL must be http:// or https://, got %q", cfg.URL)
+    }
+    if cfg.MinConfidence == "" {
+        cfg.MinConfidence = ConfidenceHigh
+    }
+    if _, ok := confidenceRank[cfg.MinConfidence]; !ok {
+        return nil, fmt.Errorf("invalid webhook confidence %q (want %s, %s or %s)", cfg.MinConfidence, ConfidenceLow, ConfidenceMedium, ConfidenceHigh)
+    }
+    if cfg.BatchSize <= 0 {
+        cfg.BatchSize = 10
+    }
+    if cfg.BatchWait <= 0 {
+        cfg.BatchWait = 30 * time.Second
+    }
+    if cfg.Interval <= 0 {
+        cfg.Interval = 2 * time.Second
+    }
+    if cfg.Retries < 0 {
+        cfg.Retries = 0
+    } else if cfg.Retries == 0 {
+        cfg.Retries = 3
+    }
+    if cfg.Timeout <= 0 {
+        cfg.Timeout = 10 * time.Second
+    }
+    src := webhookPresets[WebhookJSON]
+    if cfg.Template != "" {
+        b, err := os.ReadFile(cfg.Template)
+        if err != nil {
+            return nil, fmt.Errorf("webhook template: %w", err)
+        }
+        src = string(b)
+    } else if cfg.Preset != "" {
+        var ok bool
+        if src, ok = webhookPresets[cfg.Preset]; !ok {
+            return nil, fmt.Errorf("invalid webhook preset %q (want %s, %s, %s or %s)", cfg.Preset, WebhookJSON, WebhookSlack, WebhookMattermost, WebhookDiscord)
+        }
+    }
+    tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+        "json": func(v any) (string, error) {
+            b, err := json.Marshal(v)
+            return string(b), err
+        },
+        "trunc": func(n int, s string) string {
+            if r := []rune(s); len(r) > n {
+                return string(r[:n-1]) + "…"
+            }
+            return s
+        },
+        "join": strings.Join,
+    }).Parse(src)
+    if err != nil {
+        return nil, fmt.Errorf("webhook template: %w", err)
+    }
+    s := &WebhookSink{
+        cfg:    cfg,
+        tmpl:   tmpl,
+        client: &http.Client{Timeout: cfg.Timeout},
+        queue:   make(chan *Finding, webhookQueue),
+        done:    make(chan struct{}),
+        backoff: time.Second,
+    }
+    go s.run()
+    return s, nil
+}
+
+func (s *WebhookSink) Write(f *Finding) error {
+    level := f.Confidence
+    if level == "" {
+        level = ConfidenceMedium
+    }
+    if confidenceRank[level] < confidenceRank[s.cfg.MinConfidence] {
+        return nil
+    }
+    select {
+    case s.queue <- f:
+    default:
+        // never block the caller (usually holding a SafeSink lock) on a slow endpoint
+        atomic.AddInt64(&s.dropped, 1)
+    }
+    return nil
+}
+
+// Close sends the pending batch, waits for the sender to finish and reports findings
+// dropped because the queue was full. Later calls return the first result.
+func (s *WebhookSink) Close() error {
+    s.closeOnce.Do(func() { s.closeErr = s.close() })
+    return s.closeErr
+}
+
+func (s *WebhookSink) close() error {
+    close(s.queue)
+    <-s.done
+    if n := atomic.LoadInt64(&s.dropped); n > 0 {
+        return fmt.Errorf("webhook: %d findings dropped, the endpoint did not keep up", n)
+    }
+    return nil
+}
+
+// run batches queued findings and posts them, keeping at least Interval between requests.
+func (s *WebhookSink) run() {
+    defer close(s.done)
+    var batch []*Finding
+    var last time.Time
+    timer := time.NewTimer(time.Hour)
+    stop := func() {
+        if !timer.Stop() {
+            select { // drop a tick that fired but was not received
+            case <-timer.C:
+            default:
+            }
+        }
+    }
+    stop()
+    send := func() {
+        if len(batch) == 0 {
+            return
+        }
+        if wait := s.cfg.Interval - time.Since(last); wait > 0 {
+            time.Sleep(wait)
+        }
+        if err := s.post(batch); err != nil {
+            fmt.Printf("[webhook] %d findings not delivered: %v\n", len(batch), err)
+        }
+        last, batch = time.Now(), nil
+    }
+    for {
+        select {
+        case f, ok := <-s.queue:
+            if !ok {
+                stop()
+                send()
+                return
+            }
+            if len(batch) == 0 {
+                timer.Reset(s.cfg.BatchWait)
+            }
+            batch = append(batch, f)
+            if len(batch) >= s.cfg.BatchSize {
+                stop()
+                send()
+            }
+        case <-timer.C:
+            send()
+        }
+    }
+}
+
+// post renders and sends one batch, retrying network errors, 429 and 5xx responses
+// with exponential backoff (or the server's Retry-After).
+func (s *WebhookSink) post(batch []*Finding) error {
+    var buf bytes.Buffer
+    if err := s.tmpl.Execute(&buf, newWebhookBatch(batch)); err != nil {
+        return err
+    }
+    backoff := s.backoff
+    var err error
+    for attempt := 0; attempt <= s.cfg.Retries; attempt++ {
+        if attempt > 0 {
+            time.Sleep(backoff)
+            backoff *= 2
+        }
+        var resp *http.Response
+        resp, err = s.client.Post(s.cfg.URL, "application/json", bytes.NewReader(buf.Bytes()))
+        if err != nil {
+            continue
+        }
+        io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+        resp.Body.Close()
+        if resp.StatusCode < 300 {
+            return nil
+        }
+        err = fmt.Errorf("%s: %s", s.cfg.URL, resp.Status)
+        if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+            return err // the request itself is wrong; retrying will not help
+        }
+        if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && secs > 0 {
+            backoff = time.Duration(secs) * time.Second
+        }
+    }
+    return err
+}
+
+func newWebhookBatch(fs []*Finding) WebhookBatch {
+    lines := make([]string, 0, len(fs)+1)
+    lines = append(lines, fmt.Sprintf("pohek: %d finding(s)", len(fs)))
+    for _, f := range fs {
+        payloads := f.Payload
+        if len(f.Payloads) > 1 {
+            payloads = strings.Join(f.Payloads, " ")
+        }
+        lines = append(lines, fmt.Sprintf("[%s] %s %s%s payload=%s status=%d signals=%s", f.Confidence, f.Module, f.Host, f.Path, payloads, f.Status, strings.Join(FindingSignals(f), ",")))
+    }
+    return WebhookBatch{Count: len(fs), Findings: fs, Text: strings.Join(lines, "\n")}
+}
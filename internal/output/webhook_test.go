@@ -0,0 +1,161 @@
+package output
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// webhookServer records the JSON preset batches it receives, answering each request with
+// the next status of statuses (200 once they run out).
+type webhookServer struct {
+    *httptest.Server
+    mu       sync.Mutex
+    batches  [][]string // finding paths per delivered batch
+    requests int32
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+    ws := &webhookServer{}
+    ws.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        n := int(atomic.AddInt32(&ws.requests, 1))
+        if n <= len(statuses) && statuses[n-1] != http.StatusOK {
+            w.WriteHeader(statuses[n-1])
+            return
+        }
+        var body struct {
+            Count    int        `json:"count"`
+            Findings []*Finding `json:"findings"`
+        }
+        b, _ := io.ReadAll(r.Body)
+        if err := json.Unmarshal(b, &body); err != nil || body.Count != len(body.Findings) {
+            t.Errorf("bad webhook body %q: %v", b, err)
+        }
+        var paths []string
+        for _, f := range body.Findings {
+            paths = append(paths, f.Path)
+        }
+        ws.mu.Lock()
+        ws.batches = append(ws.batches, paths)
+        ws.mu.Unlock()
+    }))
+    t.Cleanup(ws.Close)
+    return ws
+}
+
+func newTestWebhook(t *testing.T, cfg WebhookConfig) *WebhookSink {
+    t.Helper()
+    if cfg.Interval == 0 {
+        cfg.Interval = time.Millisecond
+    }
+    s, err := NewWebhookSink(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.backoff = time.Millisecond
+    return s
+}
+
+func webhookFinding(path, confidence string) *Finding {
+    return &Finding{Module: "scpt", Host: "https://a.example", Path: path, Confidence: confidence, Signals: map[string]bool{"status": true}}
+}
+
+func TestWebhookSinkBatches(t *testing.T) {
+    ws := newWebhookServer(t)
+    s := newTestWebhook(t, WebhookConfig{URL: ws.URL, BatchSize: 2, BatchWait: time.Hour})
+    for _, p := range []string{"/a/", "/b/", "/low/", "/c/"} {
+        conf := ConfidenceHigh
+        if p == "/low/" {
+            conf = ConfidenceLow
+        }
+        s.Write(webhookFinding(p, conf))
+    }
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    want := [][]string{{"/a/", "/b/"}, {"/c/"}} // full batch, then the rest on Close; low confidence dropped
+    if got := ws.batches; len(got) != 2 || strings.Join(got[0], ",") != "/a/,/b/" || strings.Join(got[1], ",") != "/c/" {
+        t.Errorf("batches = %v, want %v", got, want)
+    }
+}
+
+func TestWebhookSinkBatchWait(t *testing.T) {
+    ws := newWebhookServer(t)
+    s := newTestWebhook(t, WebhookConfig{URL: ws.URL, BatchSize: 10, BatchWait: 20 * time.Millisecond})
+    defer s.Close()
+    s.Write(webhookFinding("/a/", ConfidenceHigh))
+    deadline := time.Now().Add(2 * time.Second)
+    for atomic.LoadInt32(&ws.requests) == 0 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    if atomic.LoadInt32(&ws.requests) != 1 {
+        t.Error("a partial batch was not sent after BatchWait")
+    }
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+    ws := newWebhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+    s := newTestWebhook(t, WebhookConfig{URL: ws.URL, BatchSize: 1, Retries: 2})
+    s.Write(webhookFinding("/a/", ConfidenceHigh))
+    if err := s.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if n := atomic.LoadInt32(&ws.requests); n != 3 || len(ws.batches) != 1 {
+        t.Errorf("%d requests, %d delivered batches; want 3 and 1", n, len(ws.batches))
+    }
+
+    // client errors are not retried
+    ws = newWebhookServer(t, http.StatusBadRequest)
+    s = newTestWebhook(t, WebhookConfig{URL: ws.URL, BatchSize: 1, Retries: 2})
+    s.Write(webhookFinding("/a/", ConfidenceHigh))
+    s.Close()
+    if n := atomic.LoadInt32(&ws.requests); n != 1 {
+        t.Errorf("%d requests after a 400, want 1", n)
+    }
+}
+
+func TestWebhookSinkDeadEndpointDoesNotBlock(t *testing.T) {
+    release := make(chan struct{})
+    var requests int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&requests, 1)
+        <-release // an endpoint that stopped answering
+    }))
+    defer srv.Close()
+    s := newTestWebhook(t, WebhookConfig{URL: srv.URL, BatchSize: 1, Retries: -1, Timeout: time.Minute})
+
+    s.Write(webhookFinding("/first/", ConfidenceHigh))
+    deadline := time.Now().Add(2 * time.Second)
+    for atomic.LoadInt32(&requests) == 0 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for i := 0; i < webhookQueue+5; i++ {
+            s.Write(webhookFinding("/more/", ConfidenceHigh))
+        }
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatal("Write blocked on a stuck endpoint")
+    }
+    if n := atomic.LoadInt64(&s.dropped); n != 5 {
+        t.Errorf("%d findings dropped, want 5", n)
+    }
+    close(release)
+    err := s.Close()
+    if err == nil || !strings.Contains(err.Error(), "5 findings dropped") {
+        t.Errorf("Close = %v, want the dropped count", err)
+    }
+    if again := s.Close(); again != err {
+        t.Errorf("second Close = %v, want %v", again, err)
+    }
+}
@@ -1,5 +1,10 @@
 package payload
 
+import (
+    "net/url"
+    "strings"
+)
+
 // Source provides traversal payloads and utilities to build test paths.
 // It allows different modes (fast/full) and external customization in future.
 type Source struct {
@@ -32,3 +37,17 @@ func (s *Source) BuildTraversal(path string) []string {
     }
     return out
 }
+
+// Family groups payloads that traverse the same way and differ only in encoding: the
+// payload is percent-decoded until it no longer changes and lower-cased, so "..%2f",
+// "%2e%2e%2f" and "../" are one family while "..%5c" and "..\" form another.
+func Family(p string) string {
+    for i := 0; i < 4; i++ {
+        d, err := url.PathUnescape(p)
+        if err != nil || d == p {
+            break
+        }
+        p = d
+    }
+    return strings.ToLower(p)
+}
@@ -2,8 +2,13 @@ package main
 
 import (
     "context"
+    "encoding/json"
     "fmt"
     "os"
+    "os/signal"
+    "strconv"
+    "strings"
+    "syscall"
     "time"
 
     "github.com/thatisuday/commando"
@@ -29,17 +34,69 @@ func main() {
 		AddFlag("port, p", "target port", commando.Int, 443).
 		AddFlag("ssl", "use ssl", commando.Bool, false).
 		AddFlag("urlfile", "file with URLs to test", commando.Bool, false).
+		AddFlag("hostfile", "basehost is a file with hostnames, host:port or origins (one per line)", commando.Bool, false).
+		AddFlag("max-host-errors", "skip a host after this many consecutive failed baselines (0 = never)", commando.Int, 10).
+		AddFlag("probe", "detect scheme/port for bare hostnames (tries https then http on --probe-ports)", commando.Bool, false).
+		AddFlag("probe-ports", "comma-separated ports tried by --probe, in order", commando.String, "443,80").
+		AddFlag("requestfile", "wordlist is a raw HTTP request file (or directory of them) with a §PATH§ or * marker", commando.Bool, false).
 		AddFlag("followredirects", "follow redirects", commando.Bool, false).
 		AddFlag("timeout", "request timeout", commando.Int, 5).
 		AddFlag("method", "HTTP method", commando.String, "GET").
+		AddFlag("raw-http", "raw socket transport for byte-exact paths: auto, always or never", commando.String, "auto").
+		AddFlag("proto", "HTTP version: auto, http1.0, http1.1, h2 (TLS only) or h2c (cleartext, prior knowledge)", commando.String, "auto").
+		AddFlag("max-body", "response body bytes kept per request, with k/m/g suffix (0 = no limit)", commando.String, "1m").
+		AddFlag("hash-body", "sha256 the full response body even when it is truncated to --max-body", commando.Bool, false).
+		AddFlag("decode", "Content-Encoding handling: auto (gzip/deflate/br are decoded) or none (bodies kept as received)", commando.String, "auto").
 		AddFlag("insecure", "Ignore TLS alerts", commando.Bool, true).
+		AddFlag("cert", "PEM client certificate for mTLS (may also contain the key)", commando.String, unset).
+		AddFlag("key", "PEM private key for --cert", commando.String, unset).
+		AddFlag("cacert", "PEM CA bundle trusted in addition to the system roots", commando.String, unset).
+		AddFlag("sni", "TLS server name to send instead of the target host", commando.String, unset).
+		AddFlag("resolve", "connect to addr for host:port, 'host:port:addr' (repeatable)", commando.String, unset).
+		AddFlag("header, H", "extra request header 'Name: value' (repeatable)", commando.String, unset).
+		AddFlag("headers-file", "file with one 'Name: value' header per line", commando.String, unset).
+		AddFlag("cookie", "cookies 'a=b; c=d' sent with every request (repeatable)", commando.String, unset).
+		AddFlag("cookie-jar", "Netscape-format cookie file; cookies are sent per domain/path", commando.String, unset).
+		AddFlag("basic", "HTTP Basic credentials user:password", commando.String, unset).
+		AddFlag("bearer", "bearer token for the Authorization header", commando.String, unset).
+		AddFlag("session", "JSON session recipe for re-authentication (login request, logout markers, extract rules)", commando.String, unset).
 		AddFlag("useragent", "set custom useragent", commando.String, "Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/85.0.4183.83 Safari/537.36").
 		AddFlag("threads, t", "number of concurrent threads", commando.Int, 15).
 		AddFlag("retry", "max retries", commando.Int, 1).
-		AddFlag("output", "path to output directory", commando.String, "no.no").
-		AddFlag("proxy", "proxy server from env variable", commando.Bool, nil).
-		AddFlag("proxy-url", "proxy server from env variable", commando.String, "proxy").
+		AddFlag("output", "directory for per-host JSONL findings (without any output option findings go to stdout)", commando.String, unset).
+		AddFlag("output-single", "write all hosts to one JSONL file: --output names the file instead of a directory", commando.Bool, false).
+		AddFlag("output-gzip", "gzip the JSONL output (per-host files are named host.jsonl.gz)", commando.Bool, false).
+		AddFlag("stdout", "also print findings to stdout when writing other outputs", commando.Bool, false).
+		AddFlag("sinks", "JSON file declaring outputs with per-output filters (see docs/ARCHITECTURE.md)", commando.String, unset).
+		AddFlag("sarif", "also write findings as a SARIF 2.1.0 log to this file", commando.String, unset).
+		AddFlag("sqlite", "also store findings in this SQLite database (one scan per run; see the query command)", commando.String, unset).
+		AddFlag("aggregate", "merge findings with the same host, path and signals into one finding listing all payloads", commando.Bool, false).
+		AddFlag("aggregate-window", "seconds without new payloads after which an aggregated finding is written (0 = at the end)", commando.Int, 30).
+		AddFlag("aggregate-subtree", "with --aggregate, collapse this many sibling paths with the same signals into one finding for their parent (0 = off; written at the end)", commando.Int, 0).
+		AddFlag("webhook", "POST findings to this webhook URL", commando.String, unset).
+		AddFlag("webhook-preset", "webhook payload: json, slack, mattermost or discord", commando.String, "json").
+		AddFlag("webhook-template", "text/template file for the webhook payload (overrides --webhook-preset)", commando.String, unset).
+		AddFlag("webhook-min-confidence", "only send findings with at least this confidence: low, medium or high", commando.String, "high").
+		AddFlag("webhook-batch", "findings per webhook request", commando.Int, 10).
+		AddFlag("webhook-wait", "seconds to wait for a webhook batch to fill", commando.Int, 30).
+		AddFlag("webhook-rate", "maximum webhook requests per minute", commando.Int, 30).
+		AddFlag("webhook-retries", "retries of a failed webhook request, with exponential backoff", commando.Int, 3).
+		AddFlag("evidence", "store the raw requests and responses behind each finding in this directory", commando.String, unset).
+		AddFlag("html", "also write a self-contained HTML report to this file at the end of the run", commando.String, unset).
+		AddFlag("proxy", "kept for compatibility: HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used whenever no --proxy-url/--proxy-file is given", commando.Bool, nil).
+		AddFlag("proxy-url", "proxy URL(s), comma-separated or repeated: http://, https://, socks5:// (local DNS) or socks5h:// (proxy DNS), optionally user:pass@", commando.String, unset).
+		AddFlag("proxy-file", "file with one proxy URL per line", commando.String, unset).
+		AddFlag("proxy-rotate", "proxy list rotation: round-robin or sticky (one proxy per target host)", commando.String, "round-robin").
+		AddFlag("proxy-auth", "user:password for proxies given without credentials", commando.String, unset).
+		AddFlag("traffic", "record exchanges to this file: HAR if it ends in .har, rotating JSONL otherwise", commando.String, unset).
+		AddFlag("traffic-mode", "exchanges to record: all, or findings (only those behind a finding)", commando.String, "all").
+		AddFlag("traffic-max-body", "body bytes recorded per request/response, with k/m/g suffix", commando.String, "64k").
+		AddFlag("traffic-rotate", "rotate the JSONL traffic log at this size, with k/m/g suffix", commando.String, "100m").
+		AddFlag("traffic-no-redact", "keep Cookie, Set-Cookie and Authorization values in the traffic log", commando.Bool, false).
+		AddFlag("traffic-redact", "further header name to redact in the traffic log and findings (repeatable)", commando.String, unset).
+		AddFlag("findings-no-redact", "keep Cookie, Set-Cookie and Authorization values in findings (request, curl/python snippets, response snapshots)", commando.Bool, false).
 		AddFlag("scpt", "enable Secondary Context Path Traversal module", commando.Bool, true).
+		AddFlag("scpt-protos", "comma-separated protocols (see --proto) every scpt payload is repeated over", commando.String, unset).
         SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
             // Gather CLI values
             basehost := args["basehost"].Value
@@ -50,13 +107,50 @@ func main() {
             timeout, _ := flags["timeout"].GetInt()
             userAgent, _ := flags["useragent"].GetString()
             threads, _ := flags["threads"].GetInt()
-            outdir, _ := flags["output"].GetString()
+            outdir := optString("output")
             retries, _ := flags["retry"].GetInt()
             insecure, _ := flags["insecure"].GetBool()
             method, _ := flags["method"].GetString()
+            rawHTTP, _ := flags["raw-http"].GetString()
+            protocol, _ := flags["proto"].GetString()
             urlfile, _ := flags["urlfile"].GetBool()
-            proxy, _ := flags["proxy"].GetBool()
-            proxyurl, _ := flags["proxy-url"].GetString()
+            requestfile, _ := flags["requestfile"].GetBool()
+            hostfile, _ := flags["hostfile"].GetBool()
+            maxHostErrors, _ := flags["max-host-errors"].GetInt()
+            probe, _ := flags["probe"].GetBool()
+            probePortsRaw, _ := flags["probe-ports"].GetString()
+            probePorts, err := parsePorts(probePortsRaw)
+            if err != nil {
+                fmt.Printf("[!] invalid --probe-ports: %v\n", err)
+                os.Exit(1)
+            }
+            maxBodyRaw, _ := flags["max-body"].GetString()
+            maxBody, err := parseSize(maxBodyRaw)
+            if err != nil {
+                fmt.Printf("[!] invalid --max-body: %v\n", err)
+                os.Exit(1)
+            }
+            if maxBody == 0 {
+                maxBody = -1 // Options: <0 means no limit
+            }
+            trafficMaxRaw, _ := flags["traffic-max-body"].GetString()
+            trafficMax, err := parseSize(trafficMaxRaw)
+            if err != nil {
+                fmt.Printf("[!] invalid --traffic-max-body: %v\n", err)
+                os.Exit(1)
+            }
+            trafficRotateRaw, _ := flags["traffic-rotate"].GetString()
+            trafficRotate, err := parseSize(trafficRotateRaw)
+            if err != nil {
+                fmt.Printf("[!] invalid --traffic-rotate: %v\n", err)
+                os.Exit(1)
+            }
+            trafficMode, _ := flags["traffic-mode"].GetString()
+            trafficNoRedact, _ := flags["traffic-no-redact"].GetBool()
+            findingsNoRedact, _ := flags["findings-no-redact"].GetBool()
+            hashBody, _ := flags["hash-body"].GetBool()
+            decode, _ := flags["decode"].GetString()
+            proxyRotate, _ := flags["proxy-rotate"].GetString()
 
             // Build options
             opt := &config.Options{
@@ -71,11 +165,62 @@ func main() {
                 Retry:           retries,
                 NoTLSValidation: insecure,
                 Method:          method,
+                RawHTTP:         rawHTTP,
+                Protocol:        protocol,
+                MaxBody:         maxBody,
+                HashBody:        hashBody,
+                Decode:          decode,
                 URLsFile:        urlfile,
-                Proxy:           proxy,
-                ProxyUrl:        proxyurl,
+                RequestsFile:    requestfile,
+                HostsFile:       hostfile,
+                MaxHostErrors:   maxHostErrors,
+                Probe:           probe,
+                ProbePorts:      probePorts,
+                ProxyRotate:     proxyRotate,
+                ProxyAuth:       optString("proxy-auth"),
                 OutputDir:       outdir,
                 Headers:         map[string]string{},
+                CookieJar:       optString("cookie-jar"),
+                BasicAuth:       optString("basic"),
+                BearerToken:     optString("bearer"),
+                SessionFile:     optString("session"),
+                ClientCert:      optString("cert"),
+                ClientKey:       optString("key"),
+                CACert:          optString("cacert"),
+                SNI:             optString("sni"),
+                Resolve:         repeatedFlag(os.Args[1:], "--resolve"),
+                TrafficFile:     optString("traffic"),
+                TrafficMode:     trafficMode,
+                TrafficMaxBody:  trafficMax,
+                TrafficRotate:   trafficRotate,
+                TrafficNoRedact: trafficNoRedact,
+                TrafficRedact:   repeatedFlag(os.Args[1:], "--traffic-redact"),
+                NoRedact:        findingsNoRedact,
+            }
+            // Headers and cookies: file first, so that -H on the command line wins
+            if hf := optString("headers-file"); hf != "" {
+                if err := opt.LoadHeadersFile(hf); err != nil {
+                    fmt.Printf("[!] cannot load headers file: %v\n", err)
+                    os.Exit(1)
+                }
+            }
+            for _, h := range repeatedFlag(os.Args[1:], "--header", "-H") {
+                if err := opt.AddHeader(h); err != nil {
+                    fmt.Printf("[!] %v\n", err)
+                    os.Exit(1)
+                }
+            }
+            for _, c := range repeatedFlag(os.Args[1:], "--cookie") {
+                opt.AddCookie(c)
+            }
+            if pf := optString("proxy-file"); pf != "" {
+                if err := opt.LoadProxiesFile(pf); err != nil {
+                    fmt.Printf("[!] cannot load proxy file: %v\n", err)
+                    os.Exit(1)
+                }
+            }
+            for _, p := range repeatedFlag(os.Args[1:], "--proxy-url") {
+                opt.AddProxies(p)
             }
 
             // Build dependencies for the layered scanner
@@ -85,14 +230,93 @@ func main() {
                 os.Exit(1)
             }
             pay := payload.NewDefault()
-            sink := output.NewSafe(output.JSONLSink{OutputDir: opt.OutputDir})
+            // Sinks: the --sinks file first, then the ones given by single flags; stdout
+            // when nothing else is configured
+            var decls []output.SinkConfig
+            if name := optString("sinks"); name != "" {
+                cfg, err := output.LoadSinksConfig(name)
+                if err != nil {
+                    fmt.Printf("[!] cannot load sinks file: %v\n", err)
+                    os.Exit(1)
+                }
+                decls = cfg.Sinks
+            }
+            if toStdout, _ := flags["stdout"].GetBool(); toStdout {
+                decls = append(decls, output.SinkConfig{Type: output.SinkStdout})
+            }
+            if opt.OutputDir != "" {
+                jl := output.SinkConfig{Type: output.SinkJSONL, Path: opt.OutputDir}
+                jl.Single, _ = flags["output-single"].GetBool()
+                jl.Gzip, _ = flags["output-gzip"].GetBool()
+                decls = append(decls, jl)
+            }
+            for _, typ := range []string{output.SinkSARIF, output.SinkSQLite, output.SinkHTML} {
+                if name := optString(typ); name != "" {
+                    decls = append(decls, output.SinkConfig{Type: typ, Path: name})
+                }
+            }
+            if url := optString("webhook"); url != "" {
+                minConfidence, _ := flags["webhook-min-confidence"].GetString()
+                wh := output.SinkConfig{
+                    Type:     output.SinkWebhook,
+                    URL:      url,
+                    Template: optString("webhook-template"),
+                    Filter:   output.Filter{MinConfidence: minConfidence},
+                }
+                wh.Preset, _ = flags["webhook-preset"].GetString()
+                wh.Batch, _ = flags["webhook-batch"].GetInt()
+                wh.Wait, _ = flags["webhook-wait"].GetInt()
+                wh.Rate, _ = flags["webhook-rate"].GetInt()
+                if wh.Retries, _ = flags["webhook-retries"].GetInt(); wh.Retries == 0 {
+                    wh.Retries = -1
+                }
+                decls = append(decls, wh)
+            }
+            if len(decls) == 0 {
+                decls = append(decls, output.SinkConfig{Type: output.SinkStdout})
+            }
+            var sinks output.MultiSink
+            for _, d := range decls {
+                s, err := d.Build(basehost + " " + wordlist)
+                if err != nil {
+                    fmt.Printf("[!] cannot create %s output: %v\n", d.Type, err)
+                    os.Exit(1)
+                }
+                sinks = append(sinks, s)
+            }
+            var sink output.Sink = output.NewSafe(sinks)
+            if agg, _ := flags["aggregate"].GetBool(); agg {
+                window, _ := flags["aggregate-window"].GetInt()
+                subtree, _ := flags["aggregate-subtree"].GetInt()
+                sink = output.NewAggregateSink(sink, time.Duration(window)*time.Second, subtree)
+            }
+            if rec := client.Recorder(); rec != nil {
+                // findings mode: write out the exchanges each finding refers to
+                sink = output.TapSink{Inner: sink, Tap: func(f *output.Finding) { rec.Keep(f.Exchanges...) }}
+            }
 
             // Prepare engine with modules controlled by CLI flags
             deps := engine.Deps{Opts: opt, Client: client, Payloads: pay, Sink: sink}
+            if dir := optString("evidence"); dir != "" {
+                if deps.Evidence, err = output.NewEvidenceStore(dir); err != nil {
+                    fmt.Printf("[!] cannot create evidence directory: %v\n", err)
+                    os.Exit(1)
+                }
+            }
             modules := []engine.Module{}
             scptEnabled, _ := flags["scpt"].GetBool()
             if scptEnabled {
-                modules = append(modules, scpt.Module{})
+                var protos []string
+                for _, p := range strings.Split(optString("scpt-protos"), ",") {
+                    if p = strings.TrimSpace(p); p != "" {
+                        if err := httpx.CheckProto(p); err != nil {
+                            fmt.Printf("[!] --scpt-protos: %v\n", err)
+                            os.Exit(1)
+                        }
+                        protos = append(protos, p)
+                    }
+                }
+                modules = append(modules, scpt.Module{Protocols: protos})
             }
             if len(modules) == 0 {
                 fmt.Println("[!] no modules enabled; enable with --scpt")
@@ -101,13 +325,255 @@ func main() {
             eng := &engine.Engine{Deps: deps, Modules: modules}
 
 
-            // Run with a cancellable context to enable future graceful shutdowns
-            ctx := context.Background()
-            if err := eng.Run(ctx); err != nil {
+            // Interrupt/terminate stop the run; sinks and the traffic log are still closed
+            ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+            err = eng.Run(ctx)
+            interrupted := ctx.Err() != nil
+            stop()
+            if interrupted {
+                fmt.Println("[!] interrupted, writing output")
+                err = nil
+            }
+            if cerr := output.Close(sink); cerr != nil {
+                fmt.Printf("[!] cannot close output: %v\n", cerr)
+            }
+            if cerr := client.Close(); cerr != nil {
+                fmt.Printf("[!] cannot close traffic log: %v\n", cerr)
+            }
+            if err != nil {
                 fmt.Printf("[!] run error: %v\n", err)
                 os.Exit(1)
             }
         })
 		
+	commando.
+		Register("report").
+		SetShortDescription("render JSONL findings as an HTML report").
+		SetDescription("Renders findings from JSONL files, output directories or a SQLite store into a self-contained HTML report.").
+		AddArgument("inputs...", "JSONL files, output directories or SQLite stores (store.db@ID for a scan)", "").
+		AddFlag("out, o", "HTML file to write", commando.String, "report.html").
+		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
+			out, _ := flags["out"].GetString()
+			var findings []*output.Finding
+			for _, in := range commandArgs(os.Args[1:], "report", "--out", "-o") {
+				fs, err := output.LoadResults(in)
+				if err != nil {
+					fmt.Printf("[!] cannot load findings: %v\n", err)
+					os.Exit(1)
+				}
+				findings = append(findings, fs...)
+			}
+			if err := output.WriteHTMLReport(out, findings); err != nil {
+				fmt.Printf("[!] cannot write report: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[*] %d findings written to %s\n", len(findings), out)
+		})
+
+	commando.
+		Register("query").
+		SetShortDescription("list findings from a SQLite store").
+		SetDescription("Lists findings stored with --sqlite, filtered by host, module, signal, status and time.").
+		AddArgument("database", "SQLite file written with --sqlite", "").
+		AddFlag("host", "host (origin URL) contains this string", commando.String, unset).
+		AddFlag("module", "module name", commando.String, unset).
+		AddFlag("signal", "finding has this signal (e.g. status, headers)", commando.String, unset).
+		AddFlag("status", "response status code (0 = any)", commando.Int, 0).
+		AddFlag("since", "found since: 2006-01-02, RFC 3339, 36h, 7d or a weekday (e.g. tuesday)", commando.String, unset).
+		AddFlag("until", "found before, same formats as --since", commando.String, unset).
+		AddFlag("scan", "only this scan ID (0 = all)", commando.Int, 0).
+		AddFlag("latest", "only the latest scan", commando.Bool, false).
+		AddFlag("new", "only findings not seen before --since (or before their own scan)", commando.Bool, false).
+		AddFlag("limit", "maximum number of findings (0 = no limit)", commando.Int, 0).
+		AddFlag("json", "print findings as JSONL instead of text", commando.Bool, false).
+		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
+			q := output.Query{Host: optString("host"), Module: optString("module"), Signal: optString("signal")}
+			q.Status, _ = flags["status"].GetInt()
+			q.Limit, _ = flags["limit"].GetInt()
+			scan, _ := flags["scan"].GetInt()
+			q.Scan = int64(scan)
+			if latest, _ := flags["latest"].GetBool(); latest {
+				q.Scan = -1
+			}
+			q.New, _ = flags["new"].GetBool()
+			for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+				if v := optString(name); v != "" {
+					var err error
+					if *t, err = output.ParseSince(v, time.Now()); err != nil {
+						fmt.Printf("[!] --%s: %v\n", name, err)
+						os.Exit(1)
+					}
+				}
+			}
+			findings, err := output.QueryFindings(args["database"].Value, q)
+			if err != nil {
+				fmt.Printf("[!] query failed: %v\n", err)
+				os.Exit(1)
+			}
+			asJSON, _ := flags["json"].GetBool()
+			enc := json.NewEncoder(os.Stdout)
+			for _, f := range findings {
+				if asJSON {
+					_ = enc.Encode(f)
+					continue
+				}
+				fmt.Printf("%s  %-6s %s%s payload=%q status=%d confidence=%s signals=%s\n",
+					f.Timestamp.Local().Format("2006-01-02 15:04"), f.Module, f.Host, f.Path, f.Payload, f.Status, f.Confidence, strings.Join(output.FindingSignals(f), ","))
+			}
+			if !asJSON {
+				fmt.Printf("[*] %d findings\n", len(findings))
+			}
+		})
+
+	commando.
+		Register("diff").
+		SetShortDescription("compare the findings of two scans").
+		SetDescription("Reports new, resolved and changed findings between two result sets, matched by finding fingerprint. A result set is a JSONL file, an output directory, or a SQLite store (store.db = latest scan, store.db@previous, store.db@ID).").
+		AddArgument("old", "earlier result set", "").
+		AddArgument("new", "later result set", "").
+		AddFlag("json", "print the differences as JSONL", commando.Bool, false).
+		SetAction(func(args map[string]commando.ArgValue, flags map[string]commando.FlagValue) {
+			var sets [2][]*output.Finding
+			for i, name := range []string{args["old"].Value, args["new"].Value} {
+				fs, err := output.LoadResults(name)
+				if err != nil {
+					fmt.Printf("[!] cannot load findings: %v\n", err)
+					os.Exit(1)
+				}
+				sets[i] = fs
+			}
+			entries := output.DiffFindings(sets[0], sets[1])
+			if asJSON, _ := flags["json"].GetBool(); asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				for _, e := range entries {
+					_ = enc.Encode(e)
+				}
+				return
+			}
+			counts := map[string]int{}
+			for _, e := range entries {
+				counts[e.Change]++
+				f := e.Finding
+				if f == nil {
+					f = e.Previous
+				}
+				fmt.Printf("%-8s %s %s%s payload=%q status=%d signals=%s\n", e.Change, e.Fingerprint, f.Host, f.Path, f.Payload, f.Status, strings.Join(output.FindingSignals(f), ","))
+				for _, c := range e.Changes {
+					fmt.Printf("         %s\n", c)
+				}
+			}
+			fmt.Printf("[*] %d new, %d resolved, %d changed\n", counts[output.DiffNew], counts[output.DiffResolved], counts[output.DiffChanged])
+		})
+
 	commando.Parse(nil)
 }
+
+// unset is the placeholder default shown for optional string flags: commando treats an
+// empty default as "required". It is never read back, see optString.
+const unset = "none"
+
+// optString returns the value of an optional string flag, or "" when it was not given.
+// Whether it was given is read from the raw arguments rather than compared with a
+// default, so every value, "none" included, is taken as is.
+func optString(name string) string {
+    return lastFlag(os.Args[1:], "--"+name)
+}
+
+// lastFlag returns the last value given for a flag (see repeatedFlag), or "".
+func lastFlag(args []string, names ...string) string {
+    vs := repeatedFlag(args, names...)
+    if len(vs) == 0 {
+        return ""
+    }
+    return vs[len(vs)-1]
+}
+
+// repeatedFlag returns every value given for a repeatable flag, in order, in the
+// "--name value" and "--name=value" forms (short names likewise: "-H value", "-H=value";
+// commando itself rejects "-Hvalue"). commando only keeps the last occurrence and splits
+// "--name=a=b" at every "=", so the raw arguments are scanned instead.
+func repeatedFlag(args []string, names ...string) []string {
+    var out []string
+    for i := 0; i < len(args); i++ {
+        for _, n := range names {
+            if args[i] == n && i+1 < len(args) {
+                out = append(out, args[i+1])
+                i++
+                break
+            }
+            if v := strings.TrimPrefix(args[i], n+"="); v != args[i] {
+                out = append(out, v)
+                break
+            }
+        }
+    }
+    return out
+}
+
+// commandArgs returns the positional arguments given after cmd, exactly as given.
+// commando hands variadic arguments over joined with commas, which cannot be split back
+// when a file name contains one. valueFlags are the command's flags that take a value.
+func commandArgs(args []string, cmd string, valueFlags ...string) []string {
+    var out []string
+    for i, a := range args {
+        if a != cmd {
+            continue
+        }
+        rest := args[i+1:]
+    next:
+        for j := 0; j < len(rest); j++ {
+            a := rest[j]
+            switch {
+            case a == "--":
+                return append(out, rest[j+1:]...)
+            case strings.HasPrefix(a, "-") && a != "-":
+                for _, f := range valueFlags {
+                    if a == f {
+                        j++
+                        continue next
+                    }
+                }
+            default:
+                out = append(out, a)
+            }
+        }
+        break
+    }
+    return out
+}
+
+// parsePorts parses a comma-separated port list such as "443,80,8443".
+func parsePorts(raw string) ([]int, error) {
+    var out []int
+    for _, f := range strings.Split(raw, ",") {
+        f = strings.TrimSpace(f)
+        if f == "" {
+            continue
+        }
+        p, err := strconv.Atoi(f)
+        if err != nil || p <= 0 || p > 65535 {
+            return nil, fmt.Errorf("bad port %q", f)
+        }
+        out = append(out, p)
+    }
+    return out, nil
+}
+
+// parseSize parses a byte count with an optional k, m or g suffix (powers of 1024).
+func parseSize(raw string) (int64, error) {
+    s := strings.ToLower(strings.TrimSpace(raw))
+    mult := int64(1)
+    switch {
+    case strings.HasSuffix(s, "k"):
+        mult, s = 1<<10, strings.TrimSuffix(s, "k")
+    case strings.HasSuffix(s, "m"):
+        mult, s = 1<<20, strings.TrimSuffix(s, "m")
+    case strings.HasSuffix(s, "g"):
+        mult, s = 1<<30, strings.TrimSuffix(s, "g")
+    }
+    n, err := strconv.ParseInt(s, 10, 64)
+    if err != nil || n < 0 {
+        return 0, fmt.Errorf("bad size %q", raw)
+    }
+    return n * mult, nil
+}
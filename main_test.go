@@ -0,0 +1,49 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestRepeatedFlag(t *testing.T) {
+    args := []string{
+        "example.com", "wl.txt",
+        "--header", "X-A: b",
+        "--header=X-B: c=d",
+        "-H", "X-C: e",
+        "-H=X-D: f",
+        "--headers-file", "h.txt",
+        "--cookie=a=b",
+        "--cookie", "c=d",
+        "--header",
+    }
+    if got, want := repeatedFlag(args, "--header", "-H"), []string{"X-A: b", "X-B: c=d", "X-C: e", "X-D: f"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("headers = %q, want %q", got, want)
+    }
+    if got, want := repeatedFlag(args, "--cookie"), []string{"a=b", "c=d"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("cookies = %q, want %q", got, want)
+    }
+    if got := repeatedFlag(args, "--proxy-url"); got != nil {
+        t.Errorf("absent flag = %q", got)
+    }
+}
+
+func TestCommandArgs(t *testing.T) {
+    args := []string{"report", "a,b.jsonl", "-o", "r.html", "--out=x.html", "dir/", "--", "-odd.jsonl"}
+    if got, want := commandArgs(args, "report", "--out", "-o"), []string{"a,b.jsonl", "dir/", "-odd.jsonl"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("commandArgs = %q, want %q", got, want)
+    }
+}
+
+func TestLastFlag(t *testing.T) {
+    args := []string{"example.com", "wl.txt", "--output", "none", "--sarif=a.sarif", "--sarif", "b=c.sarif"}
+    if got := lastFlag(args, "--output"); got != "none" {
+        t.Errorf("output = %q, want none", got)
+    }
+    if got := lastFlag(args, "--sarif"); got != "b=c.sarif" {
+        t.Errorf("sarif = %q, want the last value", got)
+    }
+    if got := lastFlag(args, "--html"); got != "" {
+        t.Errorf("absent flag = %q", got)
+    }
+}